@@ -0,0 +1,51 @@
+//go:build js && wasm
+
+package localdb
+
+import "github.com/linden/tempdb"
+
+// a backend persists the records of a database.
+// bucket records are keyed by bucket ID, metadata records by name.
+type Backend interface {
+	// load every stored record.
+	Load() (*Records, error)
+
+	// write the changes, atomically if the backend allows it.
+	// deletes must be applied before puts, since a record can be deleted and put under the same key.
+	Write(ch *Changes) error
+
+	// close the backend.
+	Close() error
+}
+
+// a function which creates or opens the backend for the named database.
+// it reports whether the database already existed.
+type BackendFunc func(name string) (b Backend, exist bool, err error)
+
+type Records struct {
+	// the encoded buckets, by key.
+	Buckets map[tempdb.BucketID][]byte
+
+	// the metadata values, by name.
+	Meta map[string][]byte
+}
+
+type Changes struct {
+	// the records to put.
+	Records
+
+	// the keys of the bucket records to delete.
+	Deletes []tempdb.BucketID
+
+	// the path of every put bucket, by key, when the bucket index is enabled.
+	// the index is only for debugging, backends may ignore it.
+	Names map[tempdb.BucketID]string
+
+	// the encoded keys of every put bucket, by bucket ID, when the key index is enabled, see `WithKeyIndex`.
+	// backends may ignore it.
+	Keys map[tempdb.BucketID][]byte
+
+	// the keys of the put records to store as blobs, see `WithBlobBuckets`.
+	// backends may store them like any other record.
+	Blobs map[tempdb.BucketID]bool
+}
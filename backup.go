@@ -0,0 +1,273 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"syscall/js"
+	"time"
+
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+// the most snapshots kept by `WithAutoBackup`.
+const maxBackups = 16
+
+// there is no snapshot at the index.
+var ErrNoBackup = errors.New("backup does not exist")
+
+// write a snapshot of the database to indexeddb after every nth commit, keeping the last keep snapshots and
+// evicting older ones. each snapshot is a full export with its buckets compressed and encrypted as the database is,
+// see `ExportFlags`, so the backups take about keep times the size of the database. keep is capped at 16, the database must be stored in indexeddb.
+func WithAutoBackup(every, keep int) Option {
+	return func(cfg *config) {
+		cfg.backupEvery = max(every, 1)
+		cfg.backupKeep = min(keep, maxBackups)
+	}
+}
+
+// list the retained snapshots, the most recent first.
+func (db *DB) Backups() ([]ExportInfo, error) {
+	b := indexedDB(db.backend)
+	if b == nil {
+		return nil, ErrNotIndexedDB
+	}
+
+	vals, err := b.backups()
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []ExportInfo
+
+	for _, v := range vals {
+		data, err := db.cfg.snapshot(v)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := verifyExport(data, db.cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// replace the database with a retained snapshot, 0 is the most recent, see `Backups`.
+// the stored buckets are replaced the way a flush writes them, the database is left as it was in memory if it fails.
+// deferred changes which haven't been flushed are discarded.
+func (db *DB) RestoreFromBackup(index int) error {
+	b := indexedDB(db.backend)
+	if b == nil {
+		return ErrNotIndexedDB
+	}
+
+	vals, err := b.backups()
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= len(vals) {
+		return ErrNoBackup
+	}
+
+	data, err := db.cfg.snapshot(vals[index])
+	if err != nil {
+		return err
+	}
+
+	// read every bucket, verifying the snapshot before anything is replaced.
+	er, err := newExportReader(bytes.NewReader(data), db.cfg)
+	if err != nil {
+		return err
+	}
+
+	var bkts []tempdb.Bucket
+
+	// the highest bucket ID.
+	var max tempdb.BucketID
+
+	for {
+		bkt, err := er.next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		bkts = append(bkts, bkt)
+		if bkt.ID > max {
+			max = bkt.ID
+		}
+	}
+
+	// hold the write lock, so no transaction sees the state while it's replaced.
+	tx, err := db.DB.BeginReadWriteTx()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// keep the state to put back if the snapshot can't be written.
+	prev, extra, sizes := *db.State, db.cfg.extra, db.cfg.sizes
+	modified, merged, tombstones := db.modified, db.merged, db.tombstones
+
+	*db.State = *newState(bkts, max)
+
+	// the snapshot's buckets reuse the stored IDs, so forget the extra data and sizes decoded with them.
+	db.cfg.extra, db.cfg.sizes = nil, nil
+
+	// the snapshot doesn't know when its buckets were modified, merged or deleted.
+	db.modified = make(map[string]time.Time)
+	db.merged = make(map[string]time.Time)
+	db.tombstones = make(map[string]time.Time)
+
+	// delete every stored record, writing the snapshot's buckets like a flush.
+	dels := make([]tempdb.BucketID, 0, len(db.records))
+
+	for id := range db.records {
+		dels = append(dels, id)
+	}
+
+	err = db.write(bkts, dels)
+	if err != nil {
+		*db.State = prev
+		db.cfg.extra, db.cfg.sizes = extra, sizes
+		db.modified, db.merged, db.tombstones = modified, merged, tombstones
+
+		return err
+	}
+
+	db.recount()
+
+	clear(db.dirty)
+
+	return nil
+}
+
+// write a snapshot if the commit is due one, the lock must be held.
+func (db *DB) backup() error {
+	db.commits++
+
+	if db.commits%db.cfg.backupEvery != 0 {
+		return nil
+	}
+
+	b := indexedDB(db.backend)
+	if b == nil {
+		return ErrNotIndexedDB
+	}
+
+	// store the buckets like a flush does, so the export's header says how.
+	var flags ExportFlags
+
+	if db.cfg.compression {
+		flags |= ExportCompressed
+	}
+
+	if db.cfg.aead != nil {
+		flags |= ExportEncrypted
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := db.exportTo(buf, nil, flags)
+	if err != nil {
+		return err
+	}
+
+	return b.putBackup(buf.Bytes(), db.cfg.backupKeep)
+}
+
+// get the export in a stored snapshot, earlier versions encrypted the whole export rather than its buckets.
+func (cfg *config) snapshot(v []byte) ([]byte, error) {
+	if bytes.HasPrefix(v, []byte(exportMagic)) {
+		return v, nil
+	}
+
+	return cfg.decrypt(v)
+}
+
+// get the indexeddb backend, nil if the database isn't stored in indexeddb or a lazy database hasn't been created.
+func indexedDB(b Backend) *idbBackend {
+	if lb, ok := b.(*lazyBackend); ok {
+		b = lb.b
+	}
+
+	ib, _ := b.(*idbBackend)
+	return ib
+}
+
+// write a snapshot, evicting the oldest so only keep snapshots remain.
+func (b *idbBackend) putBackup(v []byte, keep int) error {
+	itx, err := b.idb.NewTransaction([]string{b.store(backupStore)}, indexeddb.ReadWriteMode)
+	if err != nil {
+		return err
+	}
+
+	b.trips.transaction()
+
+	str := itx.Store(b.store(backupStore))
+
+	// snapshots are keyed by an increasing sequence, so the keys are sorted oldest first.
+	keys, err := request(value(str).Call("getAllKeys"))
+	if err != nil {
+		return err
+	}
+
+	b.trips.get(1)
+
+	var seq uint64
+
+	if n := keys.Length(); n > 0 {
+		seq = uint64(keys.Index(n - 1).Int())
+	}
+
+	// evict the oldest snapshots, leaving room for the new one.
+	for i := 0; i <= keys.Length()-keep; i++ {
+		err = str.Delete(uint64(keys.Index(i).Int()))
+		if err != nil {
+			return err
+		}
+
+		b.trips.delete(1)
+	}
+
+	b.trips.put(1)
+
+	return str.Put(seq+1, quote(v))
+}
+
+// get every snapshot, the most recent first.
+func (b *idbBackend) backups() ([][]byte, error) {
+	itx, err := b.idb.NewTransaction([]string{b.store(backupStore)}, indexeddb.ReadMode)
+	if err != nil {
+		return nil, err
+	}
+
+	b.trips.transaction()
+
+	var vals [][]byte
+
+	err = b.load(itx.Store(b.store(backupStore)), false, func(_ js.Value, val []byte) {
+		vals = append([][]byte{val}, vals...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vals, nil
+}
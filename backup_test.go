@@ -0,0 +1,320 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestAutoBackup(t *testing.T) {
+	// snapshot every 2nd commit and keep the last 3.
+	db, err := walletdb.Create("localdb", "backup.db", WithAutoBackup(2, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a bucket in each commit, so every snapshot has a different number of buckets.
+	for i := 1; i <= 10; i++ {
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			_, err := tx.CreateTopLevelBucket([]byte(fmt.Sprintf("bucket-%d", i)))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err := db.(*DB).Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure only the snapshots of the last 3 due commits remain, the most recent first.
+	exp := []int{10, 8, 6}
+
+	if len(infos) != len(exp) {
+		t.Fatalf("expected %d backups: got %d", len(exp), len(infos))
+	}
+
+	for i, info := range infos {
+		if info.Buckets != exp[i] {
+			t.Fatalf("expected backup %d to have %d buckets: got %d", i, exp[i], info.Buckets)
+		}
+	}
+}
+
+func TestRestoreFromBackup(t *testing.T) {
+	// the name of the database.
+	nm := "restore.db"
+
+	db, err := walletdb.Create("localdb", nm, WithAutoBackup(1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// put a value, every commit writes a snapshot.
+	put := func(v string) {
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt := tx.ReadWriteBucket([]byte("bucket"))
+
+			if bkt == nil {
+				var err error
+
+				bkt, err = tx.CreateTopLevelBucket([]byte("bucket"))
+				if err != nil {
+					return err
+				}
+			}
+
+			return bkt.Put([]byte("key"), []byte(v))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put("good")
+
+	// corrupt the value, and add a bucket which wasn't in the good state.
+	put("bad")
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("extra"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// roll back to the snapshot of the good state, the 3rd most recent.
+	err = db.(*DB).RestoreFromBackup(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the good state is recovered in memory and in storage.
+	check := func(db walletdb.DB) {
+		err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+			if v := tx.ReadBucket([]byte("bucket")).Get([]byte("key")); string(v) != "good" {
+				t.Fatalf("expected good: got %s", v)
+			}
+
+			if tx.ReadBucket([]byte("extra")) != nil {
+				t.Fatal("expected the extra bucket to be removed")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check(db)
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(db)
+
+	// ensure a missing snapshot is rejected.
+	err = db.(*DB).RestoreFromBackup(3)
+	if !errors.Is(err, ErrNoBackup) {
+		t.Fatalf("expected %v: got %v", ErrNoBackup, err)
+	}
+}
+
+func TestRestoreFromBackupSharded(t *testing.T) {
+	// the name of the database.
+	nm := "restore-sharded.db"
+
+	opts := []any{nm, WithAutoBackup(1, 3), WithKeyIndex(), WithSharding(10)}
+
+	db, err := walletdb.Create("localdb", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// put 26 keys starting with different bytes, so the bucket is split into 26 shards.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("large"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 26; i++ {
+			err = bkt.Put([]byte{'a' + byte(i)}, []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// delete half the keys.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for i := 13; i < 26; i++ {
+			err := tx.ReadWriteBucket([]byte("large")).Delete([]byte{'a' + byte(i)})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// roll back to the snapshot with every key.
+	err = db.(*DB).RestoreFromBackup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the key index was rewritten with the snapshot.
+	keys, err := db.(*DB).StoredKeys([]byte("large"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 26 {
+		t.Fatalf("expected 26 indexed keys: got %d", len(keys))
+	}
+
+	// ensure the bucket is stored sharded, as the bucket's record and 26 shards.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(recs.Buckets); n != 27 {
+		t.Fatalf("expected 27 records: got %d", n)
+	}
+
+	db.Close()
+
+	db, err = walletdb.Open("localdb", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		var n int
+
+		err := tx.ReadBucket([]byte("large")).ForEach(func(k, v []byte) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if n != 26 {
+			return fmt.Errorf("expected 26 keys: got %d", n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackupFlags(t *testing.T) {
+	// the name of the database.
+	nm := "backup-flags.db"
+
+	key := bytes.Repeat([]byte{1}, 32)
+	opts := []any{nm, WithAutoBackup(1, 3), WithEncryptionKey(key), WithCompression()}
+
+	db, err := walletdb.Create("localdb", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	// put a value which compresses well, the commit writes a snapshot.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("secret"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), bytes.Repeat([]byte("hidden"), 100))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the flags round-trip through the snapshot's header.
+	infos, err := db.(*DB).Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 backup: got %d", len(infos))
+	}
+
+	if exp := ExportEncrypted | ExportCompressed; infos[0].Flags != exp {
+		t.Fatalf("expected flags %#x: got %#x", exp, infos[0].Flags)
+	}
+
+	// ensure the snapshot is an export whose buckets aren't in plaintext.
+	vals, err := indexedDB(db.(*DB).backend).backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(vals[0], []byte(exportMagic)) {
+		t.Fatal("expected the snapshot to be an export")
+	}
+
+	if bytes.Contains(vals[0], []byte("hidden")) || bytes.Contains(vals[0], []byte("secret")) {
+		t.Fatal("expected the snapshot's buckets to be encrypted")
+	}
+
+	// ensure it can't be verified without the key.
+	_, err = VerifyExport(vals[0])
+	if !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("expected %v: got %v", ErrEncrypted, err)
+	}
+
+	// ensure it can be restored.
+	err = db.(*DB).RestoreFromBackup(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		v := tx.ReadBucket([]byte("secret")).Get([]byte("key"))
+		if !bytes.Equal(v, bytes.Repeat([]byte("hidden"), 100)) {
+			return fmt.Errorf("expected the value to be restored: got %q", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,68 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+
+	"github.com/linden/tempdb"
+)
+
+// a key in a bucket, see `BatchGet`.
+type BucketKey struct {
+	// the path of the bucket, the top-level bucket's key followed by the keys of the buckets nested in it.
+	Bucket [][]byte
+
+	Key []byte
+}
+
+// get many keys across buckets in a single pass over the committed state, instead of opening every bucket through
+// a transaction. the values are returned in the order of the requests, nil if the bucket or key doesn't exist.
+func (db *DB) BatchGet(reqs []BucketKey) ([][]byte, error) {
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	// the nested buckets of every bucket, by parent ID and key.
+	type child struct {
+		parent tempdb.BucketID
+		key    string
+	}
+
+	bkts := make(map[child]*tempdb.Bucket)
+
+	state := tx.(*tempdb.Transaction).State
+
+	for i, bkt := range state.Buckets {
+		bkts[child{bkt.Parent, string(bkt.Key)}] = &state.Buckets[i]
+	}
+
+	vals := make([][]byte, len(reqs))
+
+	for i, req := range reqs {
+		id := tempdb.RootBucketID
+
+		var bkt *tempdb.Bucket
+
+		for _, k := range req.Bucket {
+			bkt = bkts[child{id, string(k)}]
+			if bkt == nil {
+				break
+			}
+
+			id = bkt.ID
+		}
+
+		if bkt == nil {
+			continue
+		}
+
+		// nested buckets are stored as keys with a nil value, so they're returned as nil.
+		vals[i] = bytes.Clone(bkt.Value[string(req.Key)])
+	}
+
+	return vals, nil
+}
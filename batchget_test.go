@@ -0,0 +1,30 @@
+//go:build js && wasm
+
+package localdb
+
+import "testing"
+
+func TestBatchGet(t *testing.T) {
+	db := populated(t, "batchget.db")
+	defer db.Close()
+
+	vals, err := db.(*DB).BatchGet([]BucketKey{
+		{Bucket: [][]byte{[]byte("parent"), []byte("child")}, Key: []byte("b")},
+		{Bucket: [][]byte{[]byte("parent")}, Key: []byte("missing")},
+		{Bucket: [][]byte{[]byte("missing")}, Key: []byte("a")},
+		{Bucket: [][]byte{[]byte("parent")}, Key: []byte("a")},
+		{Bucket: [][]byte{[]byte("parent")}, Key: []byte("child")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the values are in order, nil when missing or a nested bucket.
+	expected := []string{"2", "", "", "1", ""}
+
+	for i, v := range vals {
+		if string(v) != expected[i] || (expected[i] == "" && v != nil) {
+			t.Fatalf("expected %q at %d: got %q", expected[i], i, v)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build js && wasm
+
+package localdb
+
+import "syscall/js"
+
+// store the records of the named top-level buckets as indexeddb blobs instead of quoted strings, for buckets holding
+// large binary values such as serialized transactions. quoting can double the size of binary records, blobs are stored
+// as-is and the browser may keep large ones outside the database. a record holds a whole bucket, so buckets are
+// stored as blobs rather than single keys, including the buckets nested in them.
+// blobs are read back whether or not the option is set, only the indexeddb backend stores them.
+func WithBlobBuckets(names ...[]byte) Option {
+	return func(cfg *config) {
+		if cfg.blobs == nil {
+			cfg.blobs = make(map[string]bool)
+		}
+
+		for _, nm := range names {
+			cfg.blobs[string(nm)] = true
+		}
+	}
+}
+
+// create a javascript `Blob` holding the bytes.
+func newBlob(v []byte) js.Value {
+	return js.Global().Get("Blob").New([]any{uint8Array(v)})
+}
+
+// check if a stored value is a javascript `Blob`.
+func isBlob(val js.Value) bool {
+	blob := js.Global().Get("Blob")
+
+	return !blob.IsUndefined() && val.Type() == js.TypeObject && val.InstanceOf(blob)
+}
+
+// read the bytes of a javascript `Blob`.
+func readBlob(val js.Value) ([]byte, error) {
+	buf, err := await(val.Call("arrayBuffer"))
+	if err != nil {
+		return nil, err
+	}
+
+	return copyBytes(js.Global().Get("Uint8Array").New(buf)), nil
+}
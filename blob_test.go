@@ -0,0 +1,75 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+)
+
+func TestBlobBuckets(t *testing.T) {
+	// the name of the database and the bucket.
+	nm := "blob.db"
+	txs := []byte("transactions")
+
+	db, err := walletdb.Create("localdb", nm, WithBlobBuckets(txs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a large binary value.
+	v := make([]byte, 4<<20)
+
+	_, err = rand.Read(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket(txs)
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("tx"), v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the record is stored as a blob.
+	itx, err := db.(*DB).RawTransaction([]string{bucketStore}, indexeddb.ReadMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := itx.Store(bucketStore).GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(val) != 1 || !isBlob(val[0]) {
+		t.Fatal("expected the record to be stored as a blob")
+	}
+
+	// ensure the value is read back.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if got := tx.ReadBucket(txs).Get([]byte("tx")); !bytes.Equal(got, v) {
+			t.Fatalf("expected the %d byte value: got %d bytes", len(v), len(got))
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
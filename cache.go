@@ -0,0 +1,187 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"github.com/linden/tempdb"
+)
+
+// the path every cached record is stored under.
+const cachePath = "/localdb/"
+
+// the cache storage api is not supported, it's only available in secure contexts.
+var ErrCacheUnsupported = errors.New("cache storage is not supported")
+
+// Ensure `cacheBackend` complies with the `Backend` interface.
+var _ Backend = (*cacheBackend)(nil)
+
+// the cache storage backend stores each record as a cached response, keyed by a synthetic url.
+//
+// compared to indexeddb:
+//   - writes are not atomic, a crash during a write can leave some records updated and others not.
+//   - every record is a separate request, so loading many buckets is slower.
+//   - the browser may evict caches along with the rest of the origin's storage, it's not persistent by default either.
+type cacheBackend struct {
+	cache js.Value
+}
+
+func (b *cacheBackend) Load() (*Records, error) {
+	recs := &Records{
+		Buckets: make(map[tempdb.BucketID][]byte),
+		Meta:    make(map[string][]byte),
+	}
+
+	// get every cached request.
+	reqs, err := await(b.cache.Call("keys"))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < reqs.Length(); i++ {
+		req := reqs.Index(i)
+
+		// parse the store and key from the url.
+		str, key, err := parseCacheURL(req.Get("url").String())
+		if err != nil {
+			return nil, err
+		}
+
+		// get the response.
+		res, err := await(b.cache.Call("match", req))
+		if err != nil {
+			return nil, err
+		}
+
+		// read the body.
+		buf, err := await(res.Call("arrayBuffer"))
+		if err != nil {
+			return nil, err
+		}
+
+		// copy the body into Go.
+		v := copyBytes(js.Global().Get("Uint8Array").New(buf))
+
+		switch str {
+		case bucketStore:
+			id, err := strconv.ParseUint(key, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			recs.Buckets[tempdb.BucketID(id)] = v
+
+		case metaStore:
+			recs.Meta[key] = v
+		}
+	}
+
+	return recs, nil
+}
+
+func (b *cacheBackend) Write(ch *Changes) error {
+	for _, id := range ch.Deletes {
+		_, err := await(b.cache.Call("delete", cacheURL(bucketStore, strconv.FormatUint(uint64(id), 10))))
+		if err != nil {
+			return err
+		}
+	}
+
+	for k, v := range ch.Meta {
+		err := b.put(cacheURL(metaStore, k), v)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id, v := range ch.Buckets {
+		err := b.put(cacheURL(bucketStore, strconv.FormatUint(uint64(id), 10)), v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *cacheBackend) Close() error {
+	return nil
+}
+
+// store a value as a response.
+func (b *cacheBackend) put(u string, v []byte) error {
+	// copy the value into javascript.
+	arr := js.Global().Get("Uint8Array").New(len(v))
+	js.CopyBytesToJS(arr, v)
+
+	// create the response.
+	res := js.Global().Get("Response").New(arr)
+
+	_, err := await(b.cache.Call("put", u, res))
+	return err
+}
+
+// CacheStorage stores the database using the cache storage api, in a cache named after the database.
+func CacheStorage(name string) (Backend, bool, error) {
+	caches := js.Global().Get("caches")
+
+	// ensure the cache storage api is supported.
+	if caches.IsUndefined() {
+		return nil, false, ErrCacheUnsupported
+	}
+
+	// prefix the name, so we don't collide with the app's own caches.
+	name = "localdb:" + name
+
+	// check if the cache already exists.
+	exist, err := await(caches.Call("has", name))
+	if err != nil {
+		return nil, false, err
+	}
+
+	// open the cache, creating it if it doesn't exist.
+	cache, err := await(caches.Call("open", name))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &cacheBackend{
+		cache: cache,
+	}, exist.Bool(), nil
+}
+
+// create the url for a record.
+func cacheURL(str, key string) string {
+	return cachePath + str + "/" + url.PathEscape(key)
+}
+
+// parse the store and key from the url of a record.
+func parseCacheURL(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+
+	// remove the prefix.
+	pth, ok := strings.CutPrefix(u.EscapedPath(), cachePath)
+	if !ok {
+		return "", "", errors.New("unexpected cached url: " + raw)
+	}
+
+	str, key, ok := strings.Cut(pth, "/")
+	if !ok {
+		return "", "", errors.New("unexpected cached url: " + raw)
+	}
+
+	key, err = url.PathUnescape(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	return str, key, nil
+}
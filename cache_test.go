@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"syscall/js"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestCacheStorage(t *testing.T) {
+	// ensure the cache storage api is available.
+	if js.Global().Get("caches").IsUndefined() {
+		t.Skip("cache storage is not supported")
+	}
+
+	// the name of the database.
+	nm := "cache.db"
+	db, err := walletdb.Create("localdb", nm, WithBackend(CacheStorage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the name of the bucket.
+	bktNm := []byte("alphabet")
+
+	// the values to be set.
+	vals := [][]byte{
+		[]byte("a"),
+		[]byte("b"),
+		[]byte("c"),
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket(bktNm)
+		if err != nil {
+			return err
+		}
+
+		// create a nested bucket, so there's more than 1 record.
+		nbkt, err := bkt.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+
+		for _, c := range vals {
+			err = nbkt.Put(c, c)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the database can't be created twice.
+	_, err = walletdb.Create("localdb", nm, WithBackend(CacheStorage))
+	if err != walletdb.ErrDbExists {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbExists, err)
+	}
+
+	db, err = walletdb.Open("localdb", nm, WithBackend(CacheStorage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket(bktNm)
+		if bkt == nil {
+			t.Fatalf("expected bucket %s to exist", bktNm)
+		}
+
+		nbkt := bkt.NestedReadBucket([]byte("nested"))
+		if nbkt == nil {
+			t.Fatal("expected the nested bucket to exist")
+		}
+
+		for _, c := range vals {
+			v := nbkt.Get(c)
+			if !bytes.Equal(c, v) {
+				t.Fatalf("expected %v but got %v", c, v)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
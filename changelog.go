@@ -0,0 +1,89 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"slices"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// the top-level buckets changed by every commit, see `RecordChanges`.
+type ChangeLog struct {
+	// the changes of every commit, oldest first.
+	// a change holds the export of a top-level bucket, or no data if the bucket was deleted.
+	Commits [][]SyncChange
+}
+
+// start recording the top-level buckets every commit changes, such as to reproduce a bug by replaying the log against a
+// copy of the database with `ApplyChangeLog`. commits are recorded as they're committed, whether or not they're
+// flushed, until `StopRecording` is called. every commit exports the buckets it changes, so it's meant for debugging.
+func (db *DB) RecordChanges() *ChangeLog {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.changeLog = &ChangeLog{}
+
+	return db.changeLog
+}
+
+// stop recording changes, see `RecordChanges`.
+func (db *DB) StopRecording() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.changeLog = nil
+}
+
+// replay a change log against the database, each commit in its own transaction. applied to the state the recording
+// started from, such as a copy made with `Export`, it reaches the state the recording stopped at.
+func ApplyChangeLog(db *DB, log *ChangeLog) error {
+	for _, changes := range log.Commits {
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			for _, ch := range changes {
+				err := tx.(*Transaction).replace(ch)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record the top-level buckets a commit changed, the lock must be held.
+func (db *DB) record(changed map[string]bool) error {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// sort the names, so the log is stable.
+	nms := make([]string, 0, len(changed))
+
+	for nm := range changed {
+		nms = append(nms, nm)
+	}
+
+	slices.Sort(nms)
+
+	changes := make([]SyncChange, 0, len(nms))
+
+	for _, nm := range nms {
+		ch, err := db.change(nm)
+		if err != nil {
+			return err
+		}
+
+		changes = append(changes, ch)
+	}
+
+	db.changeLog.Commits = append(db.changeLog.Commits, changes)
+
+	return nil
+}
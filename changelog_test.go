@@ -0,0 +1,94 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestChangeLog(t *testing.T) {
+	db := populated(t, "changelog.db")
+	defer db.Close()
+
+	// copy the database before recording.
+	data, err := db.(*DB).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := db.(*DB).RecordChanges()
+
+	putValue(t, db, "wallet", "keys")
+	putValue(t, db, "cache", "fees")
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt := tx.ReadWriteBucket([]byte("parent"))
+
+		err := bkt.DeleteNestedBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put([]byte("a"), []byte("3"))
+		if err != nil {
+			return err
+		}
+
+		return tx.DeleteTopLevelBucket([]byte("cache"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.(*DB).StopRecording()
+
+	// ensure changes after stopping aren't recorded.
+	putValue(t, db, "other", "value")
+
+	if n := len(log.Commits); n != 3 {
+		t.Fatalf("expected 3 commits: got %d", n)
+	}
+
+	// replay the log against the copy.
+	err = ImportFrom("changelog-replay.db", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := walletdb.Open("localdb", "changelog-replay.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer replay.Close()
+
+	err = ApplyChangeLog(replay.(*DB), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the replay reaches the state the recording stopped at.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket([]byte("other"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := db.(*DB).Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := replay.(*DB).Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Fatal("expected the replay to reach an identical state")
+	}
+}
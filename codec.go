@@ -0,0 +1,249 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// a codec encodes buckets to be stored.
+type Codec interface {
+	Encode(bkt *tempdb.Bucket) ([]byte, error)
+	Decode(v []byte) (tempdb.Bucket, error)
+}
+
+var (
+	// encode buckets with gob, this is the default codec.
+	Gob Codec = gobCodec{}
+
+	// encode buckets as JSON, which can be inspected from the browser's devtools.
+	JSON Codec = jsonCodec{}
+)
+
+// Ensure `gobCodec` complies with the `Codec` interface.
+var _ Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(bkt *tempdb.Bucket) ([]byte, error) {
+	return encode(bkt)
+}
+
+func (gobCodec) Decode(v []byte) (tempdb.Bucket, error) {
+	return decode(v)
+}
+
+func (c gobCodec) encodeExtra(bkt *tempdb.Bucket, extra map[string][]byte) ([]byte, error) {
+	return c.encodeSized(bkt, extra, 0)
+}
+
+func (gobCodec) encodeSized(bkt *tempdb.Bucket, extra map[string][]byte, size int) ([]byte, error) {
+	cb := canonicalize(bkt)
+	cb.Extra = sorted(extra)
+
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+
+	err := gob.NewEncoder(buf).Encode(cb)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) decodeExtra(v []byte) (tempdb.Bucket, map[string][]byte, error) {
+	var cb canonical
+
+	// decode the bucket, records from before the canonical form decode their values into a map.
+	err := gobDecode(v, &cb)
+	if err != nil {
+		return tempdb.Bucket{}, nil, err
+	}
+
+	return cb.bucket(), cb.extra(), nil
+}
+
+// Ensure `jsonCodec` complies with the `Codec` interface.
+var _ Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (c jsonCodec) Encode(bkt *tempdb.Bucket) ([]byte, error) {
+	return c.encodeExtra(bkt, nil)
+}
+
+func (c jsonCodec) Decode(v []byte) (tempdb.Bucket, error) {
+	bkt, _, err := c.decodeExtra(v)
+	return bkt, err
+}
+
+func (jsonCodec) encodeExtra(bkt *tempdb.Bucket, extra map[string][]byte) ([]byte, error) {
+	cb := canonicalize(bkt)
+	cb.Extra = sorted(extra)
+
+	return json.Marshal(cb)
+}
+
+func (jsonCodec) decodeExtra(v []byte) (tempdb.Bucket, map[string][]byte, error) {
+	var cb canonical
+
+	err := json.Unmarshal(v, &cb)
+	if err != nil {
+		return tempdb.Bucket{}, nil, err
+	}
+
+	return cb.bucket(), cb.extra(), nil
+}
+
+// a codec which keeps the opaque extra data newer versions store with a record, see `canonical`.
+// custom codecs don't, so their records can't carry extra data.
+type extraCodec interface {
+	encodeExtra(bkt *tempdb.Bucket, extra map[string][]byte) ([]byte, error)
+	decodeExtra(v []byte) (tempdb.Bucket, map[string][]byte, error)
+}
+
+// encode a bucket with the codec, along with its extra data.
+func encodeExtra(c Codec, bkt *tempdb.Bucket, extra map[string][]byte) ([]byte, error) {
+	if ec, ok := c.(extraCodec); ok && extra != nil {
+		return ec.encodeExtra(bkt, extra)
+	}
+
+	return c.Encode(bkt)
+}
+
+// a codec which can preallocate a bucket's encoding, given the size of its last encoding.
+type sizedCodec interface {
+	encodeSized(bkt *tempdb.Bucket, extra map[string][]byte, size int) ([]byte, error)
+}
+
+// encode a bucket with the codec along with its extra data, preallocating size bytes if the codec supports it.
+func encodeSized(c Codec, bkt *tempdb.Bucket, extra map[string][]byte, size int) ([]byte, error) {
+	if sc, ok := c.(sizedCodec); ok {
+		return sc.encodeSized(bkt, extra, size)
+	}
+
+	return encodeExtra(c, bkt, extra)
+}
+
+// decode a bucket with the codec, along with its extra data.
+func decodeExtra(c Codec, v []byte) (tempdb.Bucket, map[string][]byte, error) {
+	if ec, ok := c.(extraCodec); ok {
+		return ec.decodeExtra(v)
+	}
+
+	bkt, err := c.Decode(v)
+	return bkt, nil, err
+}
+
+// a bucket in a canonical form, the values are a list sorted by key so equal buckets always encode to the same bytes.
+// maps aren't encoded in a stable order, and JSON object keys must be UTF-8.
+type canonical struct {
+	ID     tempdb.BucketID
+	Parent tempdb.BucketID
+	Key    []byte
+	Values []canonicalValue
+
+	// the values of gob records from before the canonical form.
+	Value map[string][]byte `json:",omitempty"`
+
+	// opaque data added by newer versions sorted by key, kept untouched when the bucket is rewritten so upgrades are
+	// forward-compatible. newer versions should store new fields here rather than adding them to the record.
+	Extra []canonicalValue `json:",omitempty"`
+}
+
+type canonicalValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// convert a bucket to its canonical form.
+func canonicalize(bkt *tempdb.Bucket) *canonical {
+	return &canonical{
+		ID:     bkt.ID,
+		Parent: bkt.Parent,
+		Key:    bkt.Key,
+		Values: sorted(bkt.Value),
+	}
+}
+
+// convert the values to a list sorted by key.
+func sorted(m map[string][]byte) []canonicalValue {
+	var vals []canonicalValue
+
+	for k, v := range m {
+		vals = append(vals, canonicalValue{Key: []byte(k), Value: v})
+	}
+
+	slices.SortFunc(vals, func(a, b canonicalValue) int {
+		return bytes.Compare(a.Key, b.Key)
+	})
+
+	return vals
+}
+
+// get the extra data of a canonical bucket, nil if it has none.
+func (cb *canonical) extra() map[string][]byte {
+	if len(cb.Extra) == 0 {
+		return nil
+	}
+
+	extra := make(map[string][]byte)
+
+	for _, cv := range cb.Extra {
+		extra[string(cv.Key)] = cv.Value
+	}
+
+	return extra
+}
+
+// convert a canonical bucket back to a bucket.
+func (cb *canonical) bucket() tempdb.Bucket {
+	bkt := tempdb.Bucket{
+		ID:     cb.ID,
+		Parent: cb.Parent,
+		Key:    cb.Key,
+		Value:  make(map[string][]byte),
+	}
+
+	for k, v := range cb.Value {
+		bkt.Value[k] = v
+	}
+
+	for _, cv := range cb.Values {
+		bkt.Value[string(cv.Key)] = cv.Value
+	}
+
+	return bkt
+}
+
+// decode a stored bucket with the codec, falling back to the detected codec.
+// a database can hold records in more than 1 format while it's migrated to another codec.
+func decodeWith(c Codec, v []byte) (tempdb.Bucket, map[string][]byte, error) {
+	bkt, extra, err := decodeExtra(c, v)
+	if err == nil {
+		return bkt, extra, nil
+	}
+
+	// try the codec the record looks like it was written with.
+	if d := detect(v); d != c {
+		if bkt, extra, derr := decodeExtra(d, v); derr == nil {
+			return bkt, extra, nil
+		}
+	}
+
+	return tempdb.Bucket{}, nil, err
+}
+
+// detect the codec a record was written with, JSON records are objects and everything else is assumed to be gob.
+func detect(v []byte) Codec {
+	if bytes.HasPrefix(bytes.TrimSpace(v), []byte("{")) {
+		return JSON
+	}
+
+	return Gob
+}
@@ -0,0 +1,239 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+func TestMixedCodecs(t *testing.T) {
+	nm := "codec.db"
+
+	// put a value in a new top-level bucket.
+	put := func(db walletdb.DB, name []byte) {
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt, err := tx.CreateTopLevelBucket(name)
+			if err != nil {
+				return err
+			}
+
+			// use a key that isn't UTF-8.
+			return bkt.Put([]byte{0xff, 0x00}, name)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// write a bucket with gob.
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put(db, []byte("gob"))
+
+	// write a bucket with JSON, in the same database.
+	db, err = walletdb.Open("localdb", nm, WithCodec(JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put(db, []byte("json"))
+
+	// ensure the records are in both formats.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gobs, jsons int
+
+	for _, v := range recs.Buckets {
+		if detect(v) == JSON {
+			jsons++
+		} else {
+			gobs++
+		}
+	}
+
+	if gobs != 1 || jsons != 1 {
+		t.Fatalf("expected 1 gob and 1 JSON record: got %d and %d", gobs, jsons)
+	}
+
+	// ensure the database loads with either codec.
+	for _, c := range []Codec{Gob, JSON} {
+		db, err := walletdb.Open("localdb", nm, WithCodec(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			for _, name := range [][]byte{[]byte("gob"), []byte("json")} {
+				bkt := tx.ReadBucket(name)
+				if bkt == nil {
+					t.Fatalf("expected bucket %s to exist", name)
+				}
+
+				if v := bkt.Get([]byte{0xff, 0x00}); !bytes.Equal(v, name) {
+					t.Fatalf("expected %s: got %s", name, v)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	// the name of the database.
+	nm := "extra.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "bucket", "old")
+
+	// find the stored bucket.
+	var bkt tempdb.Bucket
+
+	for _, b := range db.(*DB).State.Buckets {
+		bkt = b
+	}
+
+	db.Close()
+
+	// store the bucket with a field from a newer version.
+	extra := map[string][]byte{"future": []byte("field")}
+
+	v, err := gobCodec{}.encodeExtra(&bkt, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _, err := IndexedDB(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{bkt.ID: v},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Close()
+
+	// rewrite the bucket, without knowing about the field.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "bucket", "new")
+
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure the field survived the rewrite.
+	rbkt, rextra, err := gobCodec{}.decodeExtra(recs.Buckets[bkt.ID])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := string(rbkt.Value["key"]); v != "new" {
+		t.Fatalf("expected the bucket to be rewritten: got %s", v)
+	}
+
+	if v := string(rextra["future"]); v != "field" {
+		t.Fatalf("expected the extra field to survive: got %v", rextra)
+	}
+}
+
+func TestExtraFieldsStable(t *testing.T) {
+	bkt := &tempdb.Bucket{ID: 1, Key: []byte("bucket"), Value: map[string][]byte{"key": []byte("value")}}
+
+	extra := map[string][]byte{
+		"a": []byte("1"),
+		"b": []byte("2"),
+		"c": []byte("3"),
+		"d": []byte("4"),
+	}
+
+	// ensure the record encodes to the same bytes every time.
+	for _, c := range []extraCodec{gobCodec{}, jsonCodec{}} {
+		first, err := c.encodeExtra(bkt, extra)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 20; i++ {
+			v, err := c.encodeExtra(bkt, extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(v, first) {
+				t.Fatalf("expected %T to encode the same bytes: got %x and %x", c, first, v)
+			}
+		}
+
+		_, rextra, err := c.decodeExtra(first)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rextra) != len(extra) || string(rextra["c"]) != "3" {
+			t.Fatalf("expected %T to decode the extra fields: got %v", c, rextra)
+		}
+	}
+
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	// a bucket of 1,000 values.
+	bkt := &tempdb.Bucket{ID: 1, Key: []byte("bench"), Value: make(map[string][]byte)}
+
+	for i := 0; i < 1000; i++ {
+		bkt.Value[string(bytes.Repeat([]byte{byte(i)}, 8))+string(rune(i))] = bytes.Repeat([]byte{byte(i)}, 64)
+	}
+
+	v, err := gobCodec{}.encodeSized(bkt, nil, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, size := range []int{0, len(v)} {
+		name := "unsized"
+		if size != 0 {
+			name = "sized"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				_, err := gobCodec{}.encodeSized(bkt, nil, size)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
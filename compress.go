@@ -0,0 +1,66 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// the prefix of compressed records, gob and JSON encodings never start with a zero byte so records are self-describing.
+const compressedPrefix = "\x00z"
+
+// compress the bucket records with DEFLATE before they're encrypted, trading CPU for storage.
+// compressed and uncompressed records are both read whether or not the option is set, so it can be enabled or disabled
+// on an existing database, a bucket's record is rewritten in the new form the next time the bucket is flushed.
+// compressed records aren't UTF-8, so it can't be used with `WithStrictUTF8`.
+func WithCompression() Option {
+	return func(cfg *config) {
+		cfg.compression = true
+	}
+}
+
+// compress only the records whose encoded size is above n bytes, like `WithCompression`. small records barely shrink
+// and still cost CPU to compress, every record is marked by its prefix so both forms are read.
+func WithMinCompressSize(n int) Option {
+	return func(cfg *config) {
+		cfg.compression = true
+		cfg.minCompress = n
+	}
+}
+
+// compress an encoded bucket, if configured and it's large enough.
+func (cfg *config) compress(v []byte) ([]byte, error) {
+	if !cfg.compression || len(v) <= cfg.minCompress {
+		return v, nil
+	}
+
+	buf := bytes.NewBufferString(compressedPrefix)
+
+	fw, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fw.Write(v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decompress an encoded bucket, if it's compressed.
+func decompress(v []byte) ([]byte, error) {
+	if !bytes.HasPrefix(v, []byte(compressedPrefix)) {
+		return v, nil
+	}
+
+	return io.ReadAll(flate.NewReader(bytes.NewReader(v[len(compressedPrefix):])))
+}
@@ -0,0 +1,115 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestCompression(t *testing.T) {
+	nm := "compress.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "a", "uncompressed")
+	putValue(t, db, "b", "uncompressed")
+
+	db.Close()
+
+	// enable compression on the uncompressed database.
+	db, err = walletdb.Open("localdb", nm, WithCompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "a", "compressed")
+
+	// ensure only the flushed bucket was rewritten compressed.
+	compressed := func(db walletdb.DB, bkt string) bool {
+		id, _ := topLevel(db.(*DB).State.Buckets, []byte(bkt))
+
+		recs, err := db.(*DB).backend.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return bytes.HasPrefix(recs.Buckets[id], []byte(compressedPrefix))
+	}
+
+	if !compressed(db, "a") || compressed(db, "b") {
+		t.Fatal("expected only the flushed bucket to be compressed")
+	}
+
+	putValue(t, db, "b", "compressed")
+
+	if !compressed(db, "b") {
+		t.Fatal("expected the bucket to be compressed once it's flushed")
+	}
+
+	db.Close()
+
+	// ensure the mixed records are read, with or without the option.
+	for _, opts := range [][]any{nil, {WithCompression()}} {
+		db, err = walletdb.Open("localdb", append([]any{nm}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, bkt := range []string{"a", "b"} {
+			if v := getValue(t, db, bkt); v != "compressed" {
+				t.Fatalf("expected compressed: got %q", v)
+			}
+		}
+
+		db.Close()
+	}
+}
+
+func TestMinCompressSize(t *testing.T) {
+	nm := "min-compress.db"
+
+	db, err := walletdb.Create("localdb", nm, WithMinCompressSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large := strings.Repeat("large", 100)
+
+	putValue(t, db, "small", "small")
+	putValue(t, db, "large", large)
+
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure only the large bucket was compressed.
+	for bkt, exp := range map[string]bool{"small": false, "large": true} {
+		id, _ := topLevel(db.(*DB).State.Buckets, []byte(bkt))
+
+		if got := bytes.HasPrefix(recs.Buckets[id], []byte(compressedPrefix)); got != exp {
+			t.Fatalf("expected %s to be compressed %t: got %t", bkt, exp, got)
+		}
+	}
+
+	db.Close()
+
+	// ensure both round-trip.
+	db, err = walletdb.Open("localdb", nm, WithMinCompressSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for bkt, exp := range map[string]string{"small": "small", "large": large} {
+		if v := getValue(t, db, bkt); v != exp {
+			t.Fatalf("expected %s to have %d bytes: got %d", bkt, len(exp), len(v))
+		}
+	}
+}
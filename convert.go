@@ -0,0 +1,163 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/linden/tempdb"
+)
+
+// read the database being converted from the named database with the options, see `Convert`.
+// the source database isn't changed, unless it's the database being converted.
+func WithSource(name string, opts ...Option) Option {
+	return func(cfg *config) {
+		cfg.source = name
+		cfg.sourceOpts = opts
+	}
+}
+
+// rewrite the named database into the configuration of the options, such as another codec, compression, sharding or
+// encryption key, so its storage can be changed without exporting and importing it.
+// the database is read with the default options, records written with any codec are read, use `WithSource` to read it
+// with other options or to write the converted buckets to a new database. in place, every record is replaced in a
+// single write, so the database is left as it was if it fails. the backups aren't converted.
+func Convert(name string, opts ...Option) error {
+	cfg := newConfig(opts...)
+
+	src := cfg.source
+	if src == "" {
+		src = name
+	}
+
+	inPlace := src == name
+
+	// read every bucket with the source's options.
+	args := []any{src}
+
+	for _, opt := range cfg.sourceOpts {
+		args = append(args, opt)
+	}
+
+	sdb, err := Open(args...)
+	if err != nil {
+		return err
+	}
+
+	old := sdb.(*DB)
+	bkts := old.State.Buckets
+
+	err = old.Close()
+	if err != nil {
+		return err
+	}
+
+	// open the database to write, it must not exist unless it's converted in place.
+	args = []any{name}
+
+	for _, opt := range opts {
+		args = append(args, opt)
+	}
+
+	db, err := newDB(!inPlace, args...)
+	if err != nil {
+		return err
+	}
+
+	defer db.backend.Close()
+
+	// set up the new encryption, storing what's needed to check the key on open.
+	meta, err := db.cfg.encryption(nil)
+	if err != nil {
+		return err
+	}
+
+	if meta == nil {
+		meta = make(map[string][]byte)
+	}
+
+	// clear the old encryption, a passphrase's parameters are only stored with a passphrase.
+	if inPlace {
+		for _, k := range []string{verifierKey, kdfKey} {
+			if _, ok := meta[k]; !ok {
+				meta[k] = []byte{}
+			}
+		}
+	}
+
+	ch := &Changes{
+		Records: Records{
+			Buckets: make(map[tempdb.BucketID][]byte),
+			Meta:    meta,
+		},
+	}
+
+	// delete every stored record, the converted buckets are put after.
+	if inPlace {
+		for id := range old.records {
+			ch.Deletes = append(ch.Deletes, id)
+		}
+	}
+
+	// keep the extra data the records were decoded with.
+	db.cfg.extra = old.cfg.extra
+
+	rts := roots(bkts)
+
+	// the path of every bucket, for the index.
+	var pths map[tempdb.BucketID]string
+
+	if db.cfg.index {
+		pths = paths(bkts)
+		ch.Names = make(map[tempdb.BucketID]string)
+	}
+
+	// the converted records stored in plaintext.
+	plain := make(map[tempdb.BucketID]bool)
+
+	for _, bkt := range bkts {
+		for _, rec := range db.cfg.split(bkt) {
+			v, err := db.cfg.marshal(&rec)
+			if err != nil {
+				return err
+			}
+
+			ch.Buckets[rec.ID], plain[rec.ID], err = db.cfg.encryptIn(v, rts[bkt.ID], recordData(bucketStore, rec.ID))
+			if err != nil {
+				return err
+			}
+
+			if pths != nil {
+				ch.Names[rec.ID], err = db.cfg.indexPath(pths[bkt.ID], rts[bkt.ID])
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	meta[countKey] = []byte(strconv.Itoa(len(ch.Buckets)))
+	meta[schemaKey] = []byte(strconv.Itoa(schemaVersion))
+	meta[plaintextKey] = formatKeys(plain)
+	meta[labelKey] = []byte(old.label)
+
+	// store how the buckets are stored, the mode changes with the records.
+	if db.cfg.strictUTF8 {
+		meta[rawKey] = []byte("1")
+	} else if inPlace {
+		meta[rawKey] = []byte{}
+	}
+
+	// keep when every top-level bucket was last modified, merged and deleted.
+	if db.cfg.timed() {
+		for k, times := range map[string]map[string]time.Time{modifiedKey: old.modified, mergedKey: old.merged, tombstonesKey: old.tombstones} {
+			meta[k], err = db.cfg.encodeTimes(times)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return db.backend.Write(ch)
+}
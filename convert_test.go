@@ -0,0 +1,110 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestConvert(t *testing.T) {
+	nm := "convert.db"
+	key := bytes.Repeat([]byte{1}, 32)
+
+	db := populated(t, nm)
+
+	err := db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// convert the gob database to encrypted JSON, in place.
+	err = Convert(nm, WithCodec(JSON), WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the database needs the key.
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("expected %v: got %v", ErrEncrypted, err)
+	}
+
+	db, err = walletdb.Open("localdb", nm, WithCodec(JSON), WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure every record is stored as JSON.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, v := range recs.Buckets {
+		v, err = db.(*DB).cfg.decryptRecord(v, bucketStore, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !json.Valid(v) {
+			t.Fatalf("expected record %d to be JSON: got %q", id, v)
+		}
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("parent"))
+
+		if v := bkt.Get([]byte("a")); string(v) != "1" {
+			t.Fatalf("expected 1: got %s", v)
+		}
+
+		if v := bkt.NestedReadBucket([]byte("child")).Get([]byte("b")); string(v) != "2" {
+			t.Fatalf("expected 2: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// convert it back to plaintext gob, as a new database.
+	err = Convert("converted.db", WithSource(nm, WithEncryptionKey(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = walletdb.Open("localdb", "converted.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if v := tx.ReadBucket([]byte("parent")).Get([]byte("a")); string(v) != "1" {
+			t.Fatalf("expected 1: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the source wasn't changed.
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("expected %v: got %v", ErrEncrypted, err)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"maps"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+// copy a top-level bucket and every bucket nested in it to a new top-level bucket, such as to snapshot an account.
+// the buckets are copied in memory, only the new bucket's records are written. it fails if dst already exists.
+func (db *DB) CopyBucket(src, dst []byte) error {
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ttx := tx.(*Transaction)
+
+		if ttx.ReadWriteBucket(src) == nil {
+			return walletdb.ErrBucketNotFound
+		}
+
+		if ttx.ReadWriteBucket(dst) != nil {
+			return walletdb.ErrBucketExists
+		}
+
+		// find the buckets to copy, by parent.
+		rts := roots(ttx.State.Buckets)
+
+		children := make(map[tempdb.BucketID][]tempdb.Bucket)
+
+		var root tempdb.Bucket
+
+		for _, bkt := range ttx.State.Buckets {
+			if rts[bkt.ID] != string(src) {
+				continue
+			}
+
+			// copy the values, so the buckets don't share them.
+			bkt.Value = maps.Clone(bkt.Value)
+
+			for k, v := range bkt.Value {
+				if v != nil {
+					bkt.Value[k] = bytes.Clone(v)
+				}
+			}
+
+			if bkt.Parent == tempdb.RootBucketID && bytes.Equal(bkt.Key, src) {
+				root = bkt
+				continue
+			}
+
+			children[bkt.Parent] = append(children[bkt.Parent], bkt)
+		}
+
+		nbkt, err := ttx.CreateTopLevelBucket(dst)
+		if err != nil {
+			return err
+		}
+
+		return restore(nbkt, &root, children)
+	})
+}
@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestCopyBucket(t *testing.T) {
+	// the name of the database.
+	nm := "copy.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("account"))
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put([]byte("balance"), []byte("100"))
+		if err != nil {
+			return err
+		}
+
+		nbkt, err := bkt.CreateBucket([]byte("addresses"))
+		if err != nil {
+			return err
+		}
+
+		return nbkt.Put([]byte("0"), []byte("bc1q"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.(*DB).CopyBucket([]byte("account"), []byte("snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the copy doesn't share the original's values.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket([]byte("account")).Put([]byte("balance"), []byte("0"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure a bucket can't be copied over another.
+	err = db.(*DB).CopyBucket([]byte("account"), []byte("snapshot"))
+	if !errors.Is(err, walletdb.ErrBucketExists) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrBucketExists, err)
+	}
+
+	// ensure the copy is stored.
+	db.Close()
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("snapshot"))
+		if bkt == nil {
+			t.Fatal("expected the copy to exist")
+		}
+
+		if v := string(bkt.Get([]byte("balance"))); v != "100" {
+			t.Fatalf("expected 100: got %s", v)
+		}
+
+		nbkt := bkt.NestedReadBucket([]byte("addresses"))
+		if nbkt == nil {
+			t.Fatal("expected the nested bucket to be copied")
+		}
+
+		if v := string(nbkt.Get([]byte("0"))); v != "bc1q" {
+			t.Fatalf("expected bc1q: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
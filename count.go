@@ -0,0 +1,79 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/linden/tempdb"
+)
+
+// the estimated bytes a map entry takes beyond its key and value, their headers and the map's bookkeeping.
+const entryOverhead = int(unsafe.Sizeof("")+unsafe.Sizeof([]byte(nil))) + 16
+
+// get the number of buckets and keys, counted as commits apply rather than by walking the database.
+// nested buckets aren't counted as keys, the error is reserved for backends which can't count.
+func (db *DB) Counts() (buckets int, keys int, err error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.buckets, db.keys, nil
+}
+
+// estimate the bytes the committed in-memory state takes, from the size of every bucket, key and value.
+// it doesn't include the garbage collector's overhead or memory held by open transactions. the error is reserved for
+// states which can't be measured.
+func (db *DB) MemoryUsage() (uint64, error) {
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var n int
+
+	for _, bkt := range tx.(*tempdb.Transaction).State.Buckets {
+		n += int(unsafe.Sizeof(bkt)) + len(bkt.Key)
+
+		for k, v := range bkt.Value {
+			n += entryOverhead + len(k) + len(v)
+		}
+	}
+
+	return uint64(n), nil
+}
+
+// count the keys in a bucket, nested buckets are stored as keys with a nil value.
+func entries(bkt *tempdb.Bucket) int {
+	var n int
+
+	for _, v := range bkt.Value {
+		if v != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+// count every bucket and key in the state, the lock must be held.
+func (db *DB) recount() {
+	db.buckets = len(db.State.Buckets)
+	db.keys = 0
+
+	for i := range db.State.Buckets {
+		db.keys += entries(&db.State.Buckets[i])
+	}
+}
+
+// get the database's counts, memory usage and flush latency, formatted for logging.
+func (db *DB) PrintStats() string {
+	bkts, keys, _ := db.Counts()
+	mem, _ := db.MemoryUsage()
+	lat := db.FlushLatency()
+
+	return fmt.Sprintf("buckets: %d, keys: %d, memory: %d bytes, flush latency: p50 %s, p95 %s, p99 %s over %d flushes",
+		bkts, keys, mem, lat.P50, lat.P95, lat.P99, lat.Samples)
+}
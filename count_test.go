@@ -0,0 +1,125 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestCounts(t *testing.T) {
+	// the name of the database.
+	nm := "counts.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the counts match.
+	check := func(db walletdb.DB, buckets, keys int) {
+		t.Helper()
+
+		bn, kn, err := db.(*DB).Counts()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bn != buckets || kn != keys {
+			t.Fatalf("expected %d buckets and %d keys: got %d and %d", buckets, keys, bn, kn)
+		}
+	}
+
+	check(db, 0, 0)
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"a", "b", "c"} {
+			err = bkt.Put([]byte(k), []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		nbkt, err := bkt.CreateBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+
+		return nbkt.Put([]byte("d"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the nested bucket isn't counted as a key.
+	check(db, 2, 4)
+
+	// overwrite a key and delete another.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt := tx.ReadWriteBucket([]byte("parent"))
+
+		err := bkt.Put([]byte("a"), []byte("other"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Delete([]byte("b"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(db, 2, 3)
+
+	// delete the nested bucket and its key.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket([]byte("parent")).DeleteNestedBucket([]byte("child"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(db, 1, 2)
+
+	// ensure the counts are restored on open.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(db, 1, 2)
+}
+
+func TestMemoryUsage(t *testing.T) {
+	db, err := walletdb.Create("localdb", "memory.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	usage := func() uint64 {
+		n, err := db.(*DB).MemoryUsage()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return n
+	}
+
+	empty := usage()
+
+	putValue(t, db, "bucket", string(bytes.Repeat([]byte{1}, 1<<16)))
+
+	// ensure the estimate grows with the data.
+	if n := usage(); n < empty+1<<16 {
+		t.Fatalf("expected at least %d bytes: got %d", empty+1<<16, n)
+	}
+}
@@ -0,0 +1,75 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// the indexeddb database was deleted or cleared while it was open, such as by the user clearing the site's data.
+// the changes since are only in memory, the app should create the database again.
+var ErrDatabaseDeleted = errors.New("database was deleted while it was open")
+
+// call fn when the indexeddb database is deleted or cleared while it's open, later writes fail with
+// `ErrDatabaseDeleted`. fn is called on its own goroutine.
+func WithOnDeleted(fn func()) Option {
+	return func(cfg *config) {
+		cfg.onDeleted = fn
+	}
+}
+
+// a backend which reports its database being deleted.
+type deletionNotifier interface {
+	notifyDeleted(fn func())
+}
+
+func (b *idbBackend) notifyDeleted(fn func()) {
+	b.onDeleted = fn
+}
+
+func (b *lazyBackend) notifyDeleted(fn func()) {
+	b.onDeleted = fn
+}
+
+// watch the connection for the database being deleted, indexeddb fires a close event when it's cleared and a
+// version change without a new version when another connection deletes it.
+func (b *idbBackend) watch() {
+	b.gone = js.FuncOf(func(this js.Value, args []js.Value) any {
+		// upgrades by other connections don't delete the database.
+		if ev := args[0]; ev.Get("type").String() == "versionchange" && !ev.Get("newVersion").IsNull() {
+			return nil
+		}
+
+		if b.deleted {
+			return nil
+		}
+
+		b.deleted = true
+
+		// close the connection, so the deletion isn't blocked.
+		b.idb.Close()
+
+		if b.onDeleted != nil {
+			go b.onDeleted()
+		}
+
+		return nil
+	})
+
+	value(b.idb).Call("addEventListener", "close", b.gone)
+	value(b.idb).Call("addEventListener", "versionchange", b.gone)
+}
+
+// stop watching the connection, if it's watched.
+func (b *idbBackend) unwatch() {
+	if b.gone.IsUndefined() {
+		return
+	}
+
+	value(b.idb).Call("removeEventListener", "close", b.gone)
+	value(b.idb).Call("removeEventListener", "versionchange", b.gone)
+
+	b.gone.Release()
+	b.gone = js.Func{}
+}
@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// the metadata key for the schema descriptor, see `WithSchemaDescriptor`.
+const descriptorKey = "schema_descriptor"
+
+// a bucket in the database's layout, see `Schema`.
+type BucketSchema struct {
+	// the bucket's key.
+	Name []byte `json:"name"`
+
+	// how the bucket's values are encoded, as described by `WithSchemaDescriptor`, empty if unknown.
+	Encoding string `json:"encoding,omitempty"`
+
+	// the nested buckets, sorted by name.
+	Buckets []BucketSchema `json:"buckets,omitempty"`
+}
+
+// store a descriptor of the database's layout in the metadata, so external tools can understand it without the app.
+// it's the JSON encoding of `Schema`, written with every flush so it follows the buckets as they're created and deleted.
+// encodings describes how the values of buckets are encoded, such as "varint" or "json", by their path such as
+// "parent/child" like `WithBucketIndex`. the descriptor is encrypted with the buckets, see `WithEncryptionKey`.
+func WithSchemaDescriptor(encodings map[string]string) Option {
+	return func(cfg *config) {
+		cfg.descriptor = true
+		cfg.encodings = encodings
+	}
+}
+
+// describe the database's layout, every top-level bucket and the buckets nested in it sorted by name.
+// unlike the schema version this is a live description of the buckets, not of how they're stored.
+func (db *DB) Schema() ([]BucketSchema, error) {
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	return db.cfg.describe(tx.(*tempdb.Transaction).State.Buckets), nil
+}
+
+// describe the layout of the buckets.
+func (cfg *config) describe(bkts []tempdb.Bucket) []BucketSchema {
+	pths := paths(bkts)
+
+	// index the nested buckets by parent.
+	children := make(map[tempdb.BucketID][]tempdb.Bucket)
+
+	for _, bkt := range bkts {
+		children[bkt.Parent] = append(children[bkt.Parent], bkt)
+	}
+
+	var walk func(id tempdb.BucketID) []BucketSchema
+
+	walk = func(id tempdb.BucketID) []BucketSchema {
+		var schs []BucketSchema
+
+		for _, bkt := range children[id] {
+			schs = append(schs, BucketSchema{
+				Name:     bkt.Key,
+				Encoding: cfg.encodings[pths[bkt.ID]],
+				Buckets:  walk(bkt.ID),
+			})
+		}
+
+		// sort the buckets so the descriptor is stable.
+		slices.SortFunc(schs, func(a, b BucketSchema) int {
+			return bytes.Compare(a.Name, b.Name)
+		})
+
+		return schs
+	}
+
+	return walk(tempdb.RootBucketID)
+}
+
+// encode the descriptor of the buckets for the metadata.
+func (cfg *config) encodeDescriptor(bkts []tempdb.Bucket) ([]byte, error) {
+	v, err := json.Marshal(cfg.describe(bkts))
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.encrypt(v)
+}
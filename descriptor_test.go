@@ -0,0 +1,74 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestSchemaDescriptor(t *testing.T) {
+	db, err := walletdb.Create("localdb", "descriptor.db", WithSchemaDescriptor(map[string]string{
+		"wallet/accounts": "json",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		wlt, err := tx.CreateTopLevelBucket([]byte("wallet"))
+		if err != nil {
+			return err
+		}
+
+		for _, nm := range []string{"accounts", "addresses"} {
+			_, err = wlt.CreateBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.CreateTopLevelBucket([]byte("cache"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []BucketSchema{
+		{Name: []byte("cache")},
+		{Name: []byte("wallet"), Buckets: []BucketSchema{
+			{Name: []byte("accounts"), Encoding: "json"},
+			{Name: []byte("addresses")},
+		}},
+	}
+
+	schs, err := db.(*DB).Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(schs, exp) {
+		t.Fatalf("expected %+v: got %+v", exp, schs)
+	}
+
+	// ensure the stored descriptor matches.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored []BucketSchema
+
+	err = json.Unmarshal(recs.Meta[descriptorKey], &stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(stored, exp) {
+		t.Fatalf("expected %+v to be stored: got %+v", exp, stored)
+	}
+}
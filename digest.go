@@ -0,0 +1,79 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// compute a SHA-256 hash of the database's content, such as to check if 2 databases are in sync.
+// databases with the same buckets, keys and values have the same digest, whatever their bucket IDs, codec or encryption.
+// it covers the committed state, including changes that haven't been flushed.
+func (db *DB) Digest() ([]byte, error) {
+	// create a read transaction, so we hash a consistent state.
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	bkts := tx.(*tempdb.Transaction).State.Buckets
+
+	// the buckets in every bucket, by parent ID.
+	children := make(map[tempdb.BucketID][]*tempdb.Bucket)
+
+	for i := range bkts {
+		children[bkts[i].Parent] = append(children[bkts[i].Parent], &bkts[i])
+	}
+
+	h := sha256.New()
+	digest(h, children, tempdb.RootBucketID)
+
+	return h.Sum(nil), nil
+}
+
+// hash the buckets in the parent, sorted by key so the digest doesn't depend on their IDs.
+func digest(h hash.Hash, children map[tempdb.BucketID][]*tempdb.Bucket, parent tempdb.BucketID) {
+	bkts := children[parent]
+
+	slices.SortFunc(bkts, func(a, b *tempdb.Bucket) int {
+		return bytes.Compare(a.Key, b.Key)
+	})
+
+	// write the number of buckets, so a bucket's values can't be confused with its siblings.
+	h.Write(binary.AppendUvarint(nil, uint64(len(bkts))))
+
+	for _, bkt := range bkts {
+		cb := canonicalize(bkt)
+
+		// prefix every field with its length, nested bucket markers have no value.
+		v := binary.AppendUvarint(nil, uint64(len(cb.Key)))
+		v = append(v, cb.Key...)
+		v = binary.AppendUvarint(v, uint64(len(cb.Values)))
+
+		for _, kv := range cb.Values {
+			v = binary.AppendUvarint(v, uint64(len(kv.Key)))
+			v = append(v, kv.Key...)
+
+			if kv.Value == nil {
+				v = append(v, 0)
+				continue
+			}
+
+			v = append(v, 1)
+			v = binary.AppendUvarint(v, uint64(len(kv.Value)))
+			v = append(v, kv.Value...)
+		}
+
+		h.Write(v)
+
+		digest(h, children, bkt.ID)
+	}
+}
@@ -0,0 +1,82 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestDigest(t *testing.T) {
+	a := populated(t, "digest-a.db")
+	defer a.Close()
+
+	// create the same content in another database, with the buckets created in another order so their IDs differ.
+	b, err := walletdb.Create("localdb", "digest-b.db", WithCodec(JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer b.Close()
+
+	err = walletdb.Update(b, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("other"))
+		if err != nil {
+			return err
+		}
+
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		nbkt, err := bkt.CreateBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+
+		err = nbkt.Put([]byte("b"), []byte("2"))
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put([]byte("a"), []byte("1"))
+		if err != nil {
+			return err
+		}
+
+		return tx.DeleteTopLevelBucket([]byte("other"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := func(db walletdb.DB) []byte {
+		v, err := db.(*DB).Digest()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return v
+	}
+
+	da := digest(a)
+
+	if db := digest(b); !bytes.Equal(da, db) {
+		t.Fatalf("expected identical digests: got %x and %x", da, db)
+	}
+
+	// ensure a single change alters the digest.
+	err = walletdb.Update(b, func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket([]byte("parent")).Put([]byte("a"), []byte("2"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db := digest(b); bytes.Equal(da, db) {
+		t.Fatal("expected the change to alter the digest")
+	}
+}
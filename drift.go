@@ -0,0 +1,159 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// how a bucket or key differs, see `Difference`.
+type DiffKind int
+
+const (
+	// the bucket or key is in the database but not the export.
+	DiffAdded DiffKind = iota
+
+	// the bucket or key is in the export but not the database.
+	DiffRemoved
+
+	// the key's value changed.
+	DiffChanged
+)
+
+// a bucket or key which differs between an export and the database.
+type Difference struct {
+	Kind DiffKind
+
+	// the path of the bucket, starting with its top-level bucket.
+	Bucket [][]byte
+
+	// the key, nil when the whole bucket was added or removed, along with every key and bucket in it.
+	Key []byte
+}
+
+// report what changed in the database since the export was created, such as to show a user what changed since a backup.
+// the differences are sorted by bucket then key, the buckets and keys of an added or removed bucket aren't reported.
+func (db *DB) DiffExport(data []byte) ([]Difference, error) {
+	old, err := readChildren(data, db.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	// group the buckets by parent, like the export.
+	cur := make(map[tempdb.BucketID][]tempdb.Bucket)
+
+	for _, bkt := range tx.(*tempdb.Transaction).State.Buckets {
+		cur[bkt.Parent] = append(cur[bkt.Parent], bkt)
+	}
+
+	var diffs []Difference
+
+	diff(old, cur, tempdb.RootBucketID, tempdb.RootBucketID, nil, &diffs)
+
+	return diffs, nil
+}
+
+// report what changed in the database since a retained snapshot, 0 is the most recent, see `Backups` and `DiffExport`.
+func (db *DB) DiffBackup(index int) ([]Difference, error) {
+	b := indexedDB(db.backend)
+	if b == nil {
+		return nil, ErrNotIndexedDB
+	}
+
+	vals, err := b.backups()
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 || index >= len(vals) {
+		return nil, ErrNoBackup
+	}
+
+	return db.DiffExport(vals[index])
+}
+
+// compare the buckets nested in the parents, the old parent from the export and the current one from the database.
+func diff(old, cur map[tempdb.BucketID][]tempdb.Bucket, oid, cid tempdb.BucketID, path [][]byte, diffs *[]Difference) {
+	// index the nested buckets by key.
+	obkts := make(map[string]*tempdb.Bucket)
+	cbkts := make(map[string]*tempdb.Bucket)
+
+	for i, bkt := range old[oid] {
+		obkts[string(bkt.Key)] = &old[oid][i]
+	}
+
+	for i, bkt := range cur[cid] {
+		cbkts[string(bkt.Key)] = &cur[cid][i]
+	}
+
+	for _, k := range union(obkts, cbkts) {
+		pth := append(slices.Clone(path), []byte(k))
+		o, c := obkts[k], cbkts[k]
+
+		switch {
+		case o == nil:
+			*diffs = append(*diffs, Difference{Kind: DiffAdded, Bucket: pth})
+
+		case c == nil:
+			*diffs = append(*diffs, Difference{Kind: DiffRemoved, Bucket: pth})
+
+		default:
+			// compare the keys, the nested buckets are compared on their own.
+			nested := func(key string) bool {
+				return slices.ContainsFunc(old[o.ID], func(b tempdb.Bucket) bool { return string(b.Key) == key }) ||
+					slices.ContainsFunc(cur[c.ID], func(b tempdb.Bucket) bool { return string(b.Key) == key })
+			}
+
+			for _, key := range union(o.Value, c.Value) {
+				if nested(key) {
+					continue
+				}
+
+				ov, ook := o.Value[key]
+				cv, cok := c.Value[key]
+
+				switch {
+				case !ook:
+					*diffs = append(*diffs, Difference{Kind: DiffAdded, Bucket: pth, Key: []byte(key)})
+
+				case !cok:
+					*diffs = append(*diffs, Difference{Kind: DiffRemoved, Bucket: pth, Key: []byte(key)})
+
+				case !bytes.Equal(ov, cv):
+					*diffs = append(*diffs, Difference{Kind: DiffChanged, Bucket: pth, Key: []byte(key)})
+				}
+			}
+
+			diff(old, cur, o.ID, c.ID, pth, diffs)
+		}
+	}
+}
+
+// get the keys in either map, sorted.
+func union[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+
+	for k := range a {
+		keys = append(keys, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
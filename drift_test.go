@@ -0,0 +1,99 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestDiffBackup(t *testing.T) {
+	// snapshot every commit.
+	db, err := walletdb.Create("localdb", "drift.db", WithAutoBackup(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		wlt, err := tx.CreateTopLevelBucket([]byte("wallet"))
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"changed", "removed", "same"} {
+			err = wlt.Put([]byte(k), []byte("old"))
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = wlt.CreateBucket([]byte("accounts"))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateTopLevelBucket([]byte("cache"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		wlt := tx.ReadWriteBucket([]byte("wallet"))
+
+		err := wlt.Put([]byte("changed"), []byte("new"))
+		if err != nil {
+			return err
+		}
+
+		err = wlt.Delete([]byte("removed"))
+		if err != nil {
+			return err
+		}
+
+		err = wlt.Put([]byte("added"), []byte("new"))
+		if err != nil {
+			return err
+		}
+
+		err = wlt.NestedReadWriteBucket([]byte("accounts")).Put([]byte("default"), []byte("new"))
+		if err != nil {
+			return err
+		}
+
+		err = tx.DeleteTopLevelBucket([]byte("cache"))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateTopLevelBucket([]byte("labels"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// compare against the stale snapshot, from before the second commit.
+	diffs, err := db.(*DB).DiffBackup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wlt := []byte("wallet")
+
+	exp := []Difference{
+		{Kind: DiffRemoved, Bucket: [][]byte{[]byte("cache")}},
+		{Kind: DiffAdded, Bucket: [][]byte{[]byte("labels")}},
+		{Kind: DiffAdded, Bucket: [][]byte{wlt}, Key: []byte("added")},
+		{Kind: DiffChanged, Bucket: [][]byte{wlt}, Key: []byte("changed")},
+		{Kind: DiffRemoved, Bucket: [][]byte{wlt}, Key: []byte("removed")},
+		{Kind: DiffAdded, Bucket: [][]byte{wlt, []byte("accounts")}, Key: []byte("default")},
+	}
+
+	if !reflect.DeepEqual(diffs, exp) {
+		t.Fatalf("expected %q: got %q", exp, diffs)
+	}
+}
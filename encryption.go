@@ -0,0 +1,411 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"github.com/linden/tempdb"
+)
+
+const (
+	// the metadata key for the sealed verifier, used to check the key on open.
+	verifierKey = "verifier"
+
+	// the metadata key for the key derivation parameters.
+	kdfKey = "kdf"
+
+	// the metadata key for the keys of the records stored in plaintext, see `WithEncryptedBuckets`.
+	plaintextKey = "plaintext"
+
+	// the plaintext of the verifier.
+	verifier = "localdb"
+
+	// the number of PBKDF2 iterations for new databases.
+	kdfIterations = 600_000
+
+	// the length of the salt for new databases.
+	saltSize = 16
+)
+
+var (
+	// the key or passphrase does not decrypt the database.
+	ErrWrongKey = errors.New("wrong encryption key or passphrase")
+
+	// the database is encrypted but no key or passphrase was given.
+	ErrEncrypted = errors.New("database is encrypted")
+
+	// a key or passphrase was given but the database is not encrypted.
+	ErrNotEncrypted = errors.New("database is not encrypted")
+
+	// the browser does not support deriving keys.
+	ErrKDFUnsupported = errors.New("deriving keys is not supported")
+)
+
+// encrypt the stored buckets with AES-GCM, the key must be 16, 24 or 32 bytes.
+// the bucket names are encrypted wherever they're stored, including the bucket index and when every bucket was
+// modified, so the names are only known with the key. the bucket count and other metadata are not encrypted, neither
+// are exports.
+// every record is sealed with its store and key, so a record moved into another's place fails to decrypt.
+func WithEncryptionKey(key []byte) Option {
+	return func(cfg *config) {
+		cfg.key = key
+	}
+}
+
+// encrypt the stored buckets with a key derived from the passphrase, see `WithEncryptionKey`.
+// the key is derived with PBKDF2-SHA256 using the browser's WebCrypto, the salt and iterations are stored
+// in the metadata so the key can be derived again on open.
+func WithPassphrase(passphrase string) Option {
+	return func(cfg *config) {
+		cfg.passphrase = passphrase
+	}
+}
+
+// only encrypt the top-level buckets fn returns true for, and the buckets nested in them, the rest are stored in
+// plaintext. it needs a key or passphrase, see `WithEncryptionKey`. the keys of the plaintext records are stored in
+// the metadata, so they aren't decrypted on open. backups are always encrypted.
+func WithEncryptedBuckets(fn func(name []byte) bool) Option {
+	return func(cfg *config) {
+		cfg.encryptBucket = fn
+	}
+}
+
+// set up the cipher from the stored metadata, or nil metadata for a new database.
+// it returns the metadata to store for a new database.
+func (cfg *config) encryption(meta map[string][]byte) (map[string][]byte, error) {
+	encrypted := cfg.key != nil || cfg.passphrase != ""
+
+	// skip databases without encryption, a converted database has an empty verifier.
+	if !encrypted && len(meta[verifierKey]) == 0 {
+		return nil, nil
+	}
+
+	if !encrypted {
+		return nil, ErrEncrypted
+	}
+
+	if meta != nil && len(meta[verifierKey]) == 0 {
+		return nil, ErrNotEncrypted
+	}
+
+	// the metadata to store, for a new database.
+	var store map[string][]byte
+
+	key := cfg.key
+
+	if cfg.passphrase != "" {
+		params := meta[kdfKey]
+
+		// create the parameters for a new database.
+		if meta == nil {
+			salt := make([]byte, saltSize)
+
+			_, err := rand.Read(salt)
+			if err != nil {
+				return nil, err
+			}
+
+			params = []byte(fmt.Sprintf("pbkdf2-sha256:%d:%x", kdfIterations, salt))
+			store = map[string][]byte{kdfKey: params}
+		}
+
+		if len(params) == 0 {
+			return nil, fmt.Errorf("%w: database was not encrypted with a passphrase", ErrWrongKey)
+		}
+
+		var err error
+
+		key, err = deriveKey(cfg.passphrase, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blk, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.aead, err = cipher.NewGCM(blk)
+	if err != nil {
+		return nil, err
+	}
+
+	// seal the verifier for a new database.
+	if meta == nil {
+		if store == nil {
+			store = make(map[string][]byte)
+		}
+
+		store[verifierKey], err = seal(cfg.aead, []byte(verifier), nil)
+		return store, err
+	}
+
+	// ensure the key opens the verifier.
+	v, err := unseal(cfg.aead, meta[verifierKey], nil)
+	if err != nil || string(v) != verifier {
+		return nil, ErrWrongKey
+	}
+
+	return nil, nil
+}
+
+// encode a bucket with the codec, transforming its name and compressing it if configured.
+func (cfg *config) marshal(bkt *tempdb.Bucket) ([]byte, error) {
+	if cfg.name != nil {
+		tbkt := *bkt
+		tbkt.Key = cfg.name(bkt.Key)
+
+		bkt = &tbkt
+	}
+
+	// keep the extra data the record was decoded with, preallocating the size of the bucket's last encoding.
+	v, err := encodeSized(cfg.codec, bkt, cfg.extra[bkt.ID], cfg.sizes[bkt.ID])
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.sizes == nil {
+		cfg.sizes = make(map[tempdb.BucketID]int)
+	}
+
+	cfg.sizes[bkt.ID] = len(v)
+
+	return cfg.compress(v)
+}
+
+// encrypt an encoded bucket in the top-level bucket with the associated data, unless it's stored in plaintext.
+// it reports whether the record is plaintext.
+func (cfg *config) encryptIn(v []byte, root string, ad []byte) ([]byte, bool, error) {
+	if cfg.aead == nil {
+		return v, false, nil
+	}
+
+	if cfg.encryptBucket != nil && !cfg.encryptBucket([]byte(root)) {
+		return v, true, nil
+	}
+
+	v, err := seal(cfg.aead, v, ad)
+	return v, false, err
+}
+
+// get a bucket's path for the index, encrypted and hex encoded like its records unless it's stored in plaintext, so
+// the index doesn't reveal the names of encrypted buckets.
+func (cfg *config) indexPath(pth string, root string) (string, error) {
+	v, plain, err := cfg.encryptIn([]byte(pth), root, nil)
+	if err != nil || plain || cfg.aead == nil {
+		return pth, err
+	}
+
+	return hex.EncodeToString(v), nil
+}
+
+// encrypt an encoded bucket, if configured.
+func (cfg *config) encrypt(v []byte) ([]byte, error) {
+	if cfg.aead == nil {
+		return v, nil
+	}
+
+	return seal(cfg.aead, v, nil)
+}
+
+// decrypt a value encrypted by `encrypt`, if configured.
+func (cfg *config) decrypt(v []byte) ([]byte, error) {
+	if cfg.aead == nil {
+		return v, nil
+	}
+
+	return unseal(cfg.aead, v, nil)
+}
+
+// get the associated data of a record, its store and key.
+func recordData(store string, key tempdb.BucketID) []byte {
+	return []byte(store + "/" + strconv.FormatUint(uint64(key), 10))
+}
+
+// decrypt a record stored in the store under the key, if configured.
+func (cfg *config) decryptRecord(v []byte, store string, key tempdb.BucketID) ([]byte, error) {
+	if cfg.aead == nil {
+		return v, nil
+	}
+
+	return unseal(cfg.aead, v, recordData(store, key))
+}
+
+// decode a bucket record stored under the key, decrypting it and reversing its name transform if configured.
+func (cfg *config) decode(key tempdb.BucketID, v []byte) (tempdb.Bucket, error) {
+	v, err := cfg.decryptRecord(v, bucketStore, key)
+	if err != nil {
+		return tempdb.Bucket{}, err
+	}
+
+	return cfg.unmarshal(v)
+}
+
+// decode a stored record, which is plaintext if its key is in the set.
+func (cfg *config) decodeRecord(key tempdb.BucketID, v []byte, plain map[tempdb.BucketID]bool) (tempdb.Bucket, error) {
+	if plain[key] {
+		return cfg.unmarshal(v)
+	}
+
+	return cfg.decode(key, v)
+}
+
+// decode a bucket with the codec, decompressing it and reversing its name transform if configured.
+func (cfg *config) unmarshal(v []byte) (tempdb.Bucket, error) {
+	v, err := decompress(v)
+	if err != nil {
+		return tempdb.Bucket{}, err
+	}
+
+	bkt, extra, err := decodeWith(cfg.codec, v)
+	if err != nil {
+		return bkt, err
+	}
+
+	// keep the extra data, so it's stored again when the bucket is rewritten.
+	if extra != nil {
+		if cfg.extra == nil {
+			cfg.extra = make(map[tempdb.BucketID]map[string][]byte)
+		}
+
+		cfg.extra[bkt.ID] = extra
+	}
+
+	if cfg.inverse == nil {
+		return bkt, nil
+	}
+
+	bkt.Key, err = cfg.inverse(bkt.Key)
+	if err != nil {
+		return tempdb.Bucket{}, fmt.Errorf("bucket %d: %w", bkt.ID, err)
+	}
+
+	return bkt, nil
+}
+
+// format the keys of the plaintext records, to be stored.
+func formatKeys(keys map[tempdb.BucketID]bool) []byte {
+	ids := make([]tempdb.BucketID, 0, len(keys))
+
+	for id, ok := range keys {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.Sort(ids)
+
+	var v []byte
+
+	for i, id := range ids {
+		if i > 0 {
+			v = append(v, ',')
+		}
+
+		v = strconv.AppendUint(v, uint64(id), 10)
+	}
+
+	return v
+}
+
+// parse the stored keys of the plaintext records.
+func parseKeys(v []byte) (map[tempdb.BucketID]bool, error) {
+	keys := make(map[tempdb.BucketID]bool)
+
+	if len(v) == 0 {
+		return keys, nil
+	}
+
+	for _, s := range strings.Split(string(v), ",") {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[tempdb.BucketID(id)] = true
+	}
+
+	return keys, nil
+}
+
+// encrypt a value with the associated data, prefixing it with a random nonce.
+func seal(aead cipher.AEAD, v []byte, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(v)+aead.Overhead())
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, v, ad), nil
+}
+
+// decrypt a value sealed by `seal` with the same associated data.
+func unseal(aead cipher.AEAD, v []byte, ad []byte) ([]byte, error) {
+	if len(v) < aead.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+
+	return aead.Open(nil, v[:aead.NonceSize()], v[aead.NonceSize():], ad)
+}
+
+// derive a key from the passphrase, using the stored parameters.
+func deriveKey(passphrase string, params []byte) ([]byte, error) {
+	// parse the parameters, formatted as "pbkdf2-sha256:<iterations>:<salt in hex>".
+	parts := strings.Split(string(params), ":")
+	if len(parts) != 3 || parts[0] != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported key derivation: %s", params)
+	}
+
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure the browser supports WebCrypto, it's only available in secure contexts.
+	subtle := js.Global().Get("crypto").Get("subtle")
+	if subtle.IsUndefined() {
+		return nil, ErrKDFUnsupported
+	}
+
+	base, err := await(subtle.Call("importKey", "raw", uint8Array([]byte(passphrase)), "PBKDF2", false, []any{"deriveBits"}))
+	if err != nil {
+		return nil, err
+	}
+
+	bits, err := await(subtle.Call("deriveBits", map[string]any{
+		"name":       "PBKDF2",
+		"hash":       "SHA-256",
+		"salt":       uint8Array(salt),
+		"iterations": iter,
+	}, base, 256))
+	if err != nil {
+		return nil, err
+	}
+
+	return copyBytes(js.Global().Get("Uint8Array").New(bits)), nil
+}
+
+// copy bytes to a javascript `Uint8Array`.
+func uint8Array(b []byte) js.Value {
+	arr := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(arr, b)
+
+	return arr
+}
@@ -0,0 +1,376 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+// create an encrypted database with a secret value.
+func secret(t *testing.T, nm string, opt Option) walletdb.DB {
+	db, err := walletdb.Create("localdb", nm, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("secret"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the value isn't stored in plaintext.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range recs.Buckets {
+		if bytes.Contains(v, []byte("secret")) {
+			t.Fatal("expected the value to be encrypted")
+		}
+	}
+
+	return db
+}
+
+// open a database and read the secret value.
+func reveal(nm string, opts ...any) ([]byte, error) {
+	db, err := walletdb.Open("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var v []byte
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		v = tx.ReadBucket([]byte("bucket")).Get([]byte("key"))
+		return nil
+	})
+
+	return v, err
+}
+
+func TestEncryptionKey(t *testing.T) {
+	nm := "encryption.db"
+	key := bytes.Repeat([]byte{1}, 32)
+
+	secret(t, nm, WithEncryptionKey(key))
+
+	v, err := reveal(nm, WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(v) != "secret" {
+		t.Fatalf("expected secret: got %s", v)
+	}
+
+	_, err = reveal(nm, WithEncryptionKey(bytes.Repeat([]byte{2}, 32)))
+	if !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected %v: got %v", ErrWrongKey, err)
+	}
+
+	_, err = reveal(nm)
+	if !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("expected %v: got %v", ErrEncrypted, err)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	nm := "passphrase.db"
+
+	db := secret(t, nm, WithPassphrase("correct horse"))
+
+	// ensure the key derivation parameters are stored.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(recs.Meta[kdfKey], []byte("pbkdf2-sha256:")) {
+		t.Fatalf("expected the key derivation parameters to be stored: got %s", recs.Meta[kdfKey])
+	}
+
+	v, err := reveal(nm, WithPassphrase("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(v) != "secret" {
+		t.Fatalf("expected secret: got %s", v)
+	}
+
+	_, err = reveal(nm, WithPassphrase("battery staple"))
+	if !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected %v: got %v", ErrWrongKey, err)
+	}
+}
+
+func TestBoundRecords(t *testing.T) {
+	nm := "bound.db"
+	key := bytes.Repeat([]byte{1}, 32)
+
+	db, err := walletdb.Create("localdb", nm, WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, nm := range []string{"a", "b"} {
+			bkt, err := tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put([]byte("key"), []byte(nm))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// swap the records of the buckets.
+	bk, _, err := IndexedDB(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := bk.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []tempdb.BucketID
+
+	for id := range recs.Buckets {
+		keys = append(keys, id)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 records: got %d", len(keys))
+	}
+
+	err = bk.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{keys[0]: recs.Buckets[keys[1]], keys[1]: recs.Buckets[keys[0]]},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bk.Close()
+
+	// ensure a record swapped into another's place is rejected.
+	_, err = walletdb.Open("localdb", nm, WithEncryptionKey(key))
+	if err == nil {
+		t.Fatal("expected the swapped records to be rejected")
+	}
+}
+
+func TestEncryptedBuckets(t *testing.T) {
+	// the name of the database.
+	nm := "partial.db"
+	key := bytes.Repeat([]byte{1}, 32)
+
+	// only encrypt the keys, the address cache is public.
+	opts := []any{nm, WithEncryptionKey(key), WithEncryptedBuckets(func(name []byte) bool {
+		return string(name) == "keys"
+	})}
+
+	db, err := walletdb.Create("localdb", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for nm, v := range map[string]string{"keys": "private", "addresses": "public"} {
+			bkt, err := tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put([]byte("value"), []byte(v))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure only the address cache is stored in plaintext.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var public bool
+
+	for _, v := range recs.Buckets {
+		if bytes.Contains(v, []byte("private")) {
+			t.Fatal("expected the keys to be encrypted")
+		}
+
+		public = public || bytes.Contains(v, []byte("public"))
+	}
+
+	if !public {
+		t.Fatal("expected the address cache to be stored in plaintext")
+	}
+
+	// ensure both buckets round-trip.
+	db, err = walletdb.Open("localdb", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for nm, exp := range map[string]string{"keys": "private", "addresses": "public"} {
+			if v := tx.ReadBucket([]byte(nm)).Get([]byte("value")); string(v) != exp {
+				t.Fatalf("expected %s: got %s", exp, v)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedNames(t *testing.T) {
+	nm := "encrypted-names.db"
+	key := bytes.Repeat([]byte{1}, 32)
+
+	// store the names in the index and in when every bucket was modified and deleted.
+	opts := []any{WithEncryptionKey(key), WithBucketIndex(), WithSyncer(&memorySyncer{})}
+
+	db, err := walletdb.Create("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "hidden", "value")
+	putValue(t, db, "deleted", "value")
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket([]byte("deleted"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure no stored value reveals the names.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored [][]byte
+
+	for _, v := range recs.Meta {
+		stored = append(stored, v)
+	}
+
+	for _, v := range recs.Buckets {
+		stored = append(stored, v)
+	}
+
+	itx, err := db.(*DB).backend.(*idbBackend).idb.NewTransaction([]string{indexStore}, indexeddb.ReadMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pths, err := request(value(itx.Store(indexStore)).Call("getAll"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pths.Length() == 0 {
+		t.Fatal("expected the bucket to be indexed")
+	}
+
+	for i := 0; i < pths.Length(); i++ {
+		stored = append(stored, []byte(pths.Index(i).String()))
+	}
+
+	for _, v := range stored {
+		if bytes.Contains(v, []byte("hidden")) || bytes.Contains(v, []byte("deleted")) {
+			t.Fatalf("expected the names to be encrypted: got %q", v)
+		}
+	}
+
+	db.Close()
+
+	// ensure the names are matched with the key.
+	db, err = walletdb.Open("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if v := getValue(t, db, "hidden"); v != "value" {
+		t.Fatalf("expected value: got %q", v)
+	}
+
+	if _, ok := db.(*DB).Tombstones()["deleted"]; !ok {
+		t.Fatal("expected the deleted bucket's tombstone")
+	}
+}
+
+func TestEncryptedTimesTampered(t *testing.T) {
+	nm := "encrypted-times.db"
+	opts := []any{WithEncryptionKey(bytes.Repeat([]byte{1}, 32)), WithSyncer(&memorySyncer{})}
+
+	db, err := walletdb.Create("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "bucket", "value")
+
+	// replace the encrypted times with plaintext ones.
+	v, err := (&config{}).encodeTimes(map[string]time.Time{"bucket": time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.(*DB).backend.Write(&Changes{Records: Records{Meta: map[string][]byte{modifiedKey: v}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure the times aren't trusted without being authenticated.
+	_, err = walletdb.Open("localdb", append([]any{nm}, opts...)...)
+	if err == nil {
+		t.Fatal("expected the plaintext times to be rejected")
+	}
+}
@@ -0,0 +1,91 @@
+//go:build js && wasm
+
+package localdb
+
+import "slices"
+
+// the number of events buffered for each subscriber, later events are dropped until the subscriber catches up.
+const eventBuffer = 16
+
+// the kind of a flush event.
+type FlushEventKind int
+
+const (
+	// a flush started writing to the backend.
+	FlushStarted FlushEventKind = iota
+
+	// a flush finished writing to the backend.
+	FlushSucceeded
+
+	// a flush failed, see `ErrNotFlushed`.
+	FlushFailed
+)
+
+// an event sent to subscribers when the database is written to the backend.
+type FlushEvent struct {
+	Kind FlushEventKind
+
+	// the number of bytes of buckets written, set when the flush succeeded.
+	Bytes int
+
+	// the error, set when the flush failed.
+	Err error
+}
+
+// subscribe to flush events, such as to show whether changes are saved.
+// events are never waited on, they're dropped while the subscriber's buffer is full.
+func (db *DB) Subscribe() <-chan FlushEvent {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	ch := make(chan FlushEvent, eventBuffer)
+	db.subscribers = append(db.subscribers, ch)
+
+	return ch
+}
+
+// stop sending flush events to the subscriber, closing the channel.
+func (db *DB) Unsubscribe(ch <-chan FlushEvent) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for i, sub := range db.subscribers {
+		if sub == ch {
+			close(sub)
+			db.subscribers = append(db.subscribers[:i], db.subscribers[i+1:]...)
+
+			return
+		}
+	}
+}
+
+// send an event to every subscriber, the lock must be held.
+func (db *DB) emit(ev FlushEvent) {
+	for _, sub := range db.subscribers {
+		select {
+		case sub <- ev:
+		default:
+		}
+	}
+}
+
+// call the function whenever a transaction is committed, once the in-memory state has changed but before it's flushed,
+// so a UI can update without waiting for storage. it's called synchronously and never for a rolled back transaction.
+// the transaction still holds the write lock, so the function can read the database but must not update it.
+func (db *DB) OnStateChange(fn func()) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.listeners = append(db.listeners, fn)
+}
+
+// call the state change listeners, without the lock so they can read the database.
+func (db *DB) stateChanged() {
+	db.lock.Lock()
+	fns := slices.Clone(db.listeners)
+	db.lock.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -0,0 +1,150 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestSubscribe(t *testing.T) {
+	var cb *crashingBackend
+
+	crashing := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		cb = &crashingBackend{Backend: b, after: math.MaxInt}
+		return cb, exist, nil
+	}
+
+	db, err := walletdb.Create("localdb", "events.db", WithBackend(crashing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evs := db.(*DB).Subscribe()
+	defer db.(*DB).Unsubscribe(evs)
+
+	// create a bucket, failing the flush if fail is set.
+	commit := func(nm string, fail bool) {
+		if fail {
+			cb.after = cb.writes
+		}
+
+		walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			_, err := tx.CreateTopLevelBucket([]byte(nm))
+			return err
+		})
+	}
+
+	// ensure the next events are the kinds.
+	expect := func(kinds ...FlushEventKind) []FlushEvent {
+		t.Helper()
+
+		var got []FlushEvent
+
+		for _, kind := range kinds {
+			select {
+			case ev := <-evs:
+				if ev.Kind != kind {
+					t.Fatalf("expected event %d: got %d", kind, ev.Kind)
+				}
+
+				got = append(got, ev)
+
+			default:
+				t.Fatalf("expected event %d: got none", kind)
+			}
+		}
+
+		return got
+	}
+
+	commit("a", false)
+
+	got := expect(FlushStarted, FlushSucceeded)
+	if got[1].Bytes == 0 {
+		t.Fatal("expected the bytes written")
+	}
+
+	commit("b", true)
+
+	got = expect(FlushStarted, FlushFailed)
+	if got[1].Err == nil {
+		t.Fatal("expected the error")
+	}
+
+	// ensure a subscriber which never reads doesn't block, even once its buffer is full.
+	db.(*DB).Subscribe()
+
+	cb.after = math.MaxInt
+
+	for i := 0; i < eventBuffer; i++ {
+		commit(fmt.Sprint(i), false)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	db, err := walletdb.Create("localdb", "statechange.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	var calls int
+
+	db.(*DB).OnStateChange(func() {
+		calls++
+
+		// ensure the listener sees the committed state.
+		err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+			if tx.ReadBucket([]byte("bucket")) == nil {
+				t.Error("expected the bucket to be committed")
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected 1 call: got %d", calls)
+	}
+
+	// ensure a rolled back transaction doesn't call the listener.
+	tx, err := db.BeginReadWriteTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.ReadWriteBucket([]byte("bucket")).Put([]byte("key"), []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected 1 call: got %d", calls)
+	}
+}
@@ -0,0 +1,402 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/linden/tempdb"
+)
+
+const (
+	// the magic number at the start of every export.
+	exportMagic = "LCDB"
+
+	// the version of the export format, version 2 added the creation time and version 3 added the flags.
+	exportVersion = 3
+
+	// the largest bucket an export is read with, well above any record the browser stores.
+	maxExportBucket = 1 << 30
+)
+
+// the flags of an export, describing how its buckets are stored.
+type ExportFlags uint8
+
+const (
+	// the buckets are compressed, see `WithCompression`.
+	ExportCompressed ExportFlags = 1 << iota
+
+	// the buckets are encrypted, see `WithEncryptionKey`.
+	ExportEncrypted
+
+	// every flag this version of localdb reads.
+	knownExportFlags = ExportCompressed | ExportEncrypted
+)
+
+var (
+	// the data is not an export.
+	ErrNotExport = errors.New("not a localdb export")
+
+	// the export was created by a newer version of localdb.
+	ErrExportVersion = errors.New("unsupported export version")
+
+	// the export's checksum does not match its contents.
+	ErrExportChecksum = errors.New("export checksum mismatch")
+
+	// the export's buckets are stored in a way this version of localdb can't read.
+	ErrExportFlags = errors.New("unsupported export flags")
+)
+
+// export the database, see `ExportTo`.
+func (db *DB) Export() ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	err := db.ExportTo(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// stream the database to the writer, 1 bucket at a time.
+// the export is self-describing, its framing doesn't depend on gob so it can be versioned and validated:
+//
+//	size     field
+//	4        the magic number, "LCDB".
+//	1        the format version, currently 3.
+//	1        the flags, see `ExportFlags`. from version 3.
+//	varint   the creation time, in unix milliseconds. from version 2.
+//	uvarint  the number of buckets.
+//	...      every bucket, its length as a uvarint followed by the gob encoded bucket, compressed then encrypted if
+//	         the flags say so.
+//	4        a big-endian CRC-32 (IEEE) checksum of everything before it.
+func (db *DB) ExportTo(w io.Writer) error {
+	return db.exportTo(w, nil, 0)
+}
+
+// write a copy of the database to the writer, as an export, see `ExportTo`. it's restored with `ImportFrom`.
+func (db *DB) Copy(w io.Writer) error {
+	return db.ExportTo(w)
+}
+
+// export only the named top-level buckets and the buckets nested in them, see `ExportTo`.
+// like `Flush`, every bucket is exported when no names are given. the export can be restored with `ImportFrom` or merged into a database with `ImportBuckets`.
+func (db *DB) ExportBuckets(names ...[]byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	err := db.exportTo(buf, names, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// export the named top-level buckets, or every bucket when names is nil, storing the buckets as the flags say.
+func (db *DB) exportTo(w io.Writer, names [][]byte, flags ExportFlags) error {
+	// create a read transaction, so we export a consistent state.
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	bkts := tx.(*tempdb.Transaction).State.Buckets
+
+	// only keep the buckets in the named top-level buckets.
+	if names != nil {
+		rts := roots(bkts)
+
+		bkts = slices.DeleteFunc(slices.Clone(bkts), func(bkt tempdb.Bucket) bool {
+			return !slices.ContainsFunc(names, func(nm []byte) bool {
+				return string(nm) == rts[bkt.ID]
+			})
+		})
+	}
+
+	// checksum everything we write.
+	sum := crc32.NewIEEE()
+	bw := bufio.NewWriter(w)
+	cw := io.MultiWriter(bw, sum)
+
+	// write the header.
+	hdr := append([]byte(exportMagic), exportVersion, byte(flags))
+	hdr = binary.AppendVarint(hdr, db.cfg.now().UnixMilli())
+	hdr = binary.AppendUvarint(hdr, uint64(len(bkts)))
+
+	_, err = cw.Write(hdr)
+	if err != nil {
+		return err
+	}
+
+	for _, bkt := range bkts {
+		// exports always use gob, whatever the database codec.
+		v, err := encode(&bkt)
+		if err != nil {
+			return err
+		}
+
+		if flags&ExportCompressed != 0 {
+			v, err = db.cfg.compress(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		if flags&ExportEncrypted != 0 {
+			v, err = db.cfg.encrypt(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		// prefix the bucket with its length.
+		_, err = cw.Write(binary.AppendUvarint(nil, uint64(len(v))))
+		if err != nil {
+			return err
+		}
+
+		_, err = cw.Write(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	// write the checksum.
+	_, err = bw.Write(sum.Sum(nil))
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// what an export contains, see `VerifyExport`.
+type ExportInfo struct {
+	// the version of the export format.
+	Version int
+
+	// how the buckets are stored, exports from before version 3 have no flags.
+	Flags ExportFlags
+
+	// when the export was created, exports from before version 2 have no creation time.
+	Created time.Time
+
+	// the number of buckets.
+	Buckets int
+}
+
+// verify an export without importing it, checking its header, every bucket and its checksum.
+// exports with encrypted buckets can't be verified without the key, they fail with `ErrEncrypted`.
+func VerifyExport(data []byte) (ExportInfo, error) {
+	return verifyExport(data, nil)
+}
+
+// verify an export, decrypting its buckets with the config if they're encrypted.
+func verifyExport(data []byte, cfg *config) (ExportInfo, error) {
+	er, err := newExportReader(bytes.NewReader(data), cfg)
+	if err != nil {
+		return ExportInfo{}, err
+	}
+
+	info := ExportInfo{
+		Version: er.version,
+		Flags:   er.flags,
+		Created: er.created,
+		Buckets: int(er.left),
+	}
+
+	for {
+		_, err := er.next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return ExportInfo{}, err
+		}
+	}
+
+	// ensure nothing follows the checksum.
+	if _, err := er.r.ReadByte(); err != io.EOF {
+		return ExportInfo{}, fmt.Errorf("%w: unexpected data after the checksum", ErrExportChecksum)
+	}
+
+	return info, nil
+}
+
+// an export being read.
+type exportReader struct {
+	r   *bufio.Reader
+	sum hash.Hash32
+
+	// the version of the export format, its flags and when it was created.
+	version int
+	flags   ExportFlags
+	created time.Time
+
+	// decrypts the buckets, if they're encrypted.
+	cfg *config
+
+	// the number of buckets left to read.
+	left uint64
+}
+
+// read from the export, adding the bytes to the checksum.
+func (er *exportReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	er.sum.Write(p[:n])
+
+	return n, err
+}
+
+func (er *exportReader) ReadByte() (byte, error) {
+	b, err := er.r.ReadByte()
+	if err == nil {
+		er.sum.Write([]byte{b})
+	}
+
+	return b, err
+}
+
+// read the header of an export, it's validated before any bucket is read.
+// encrypted buckets are decrypted with the config, which may be nil if the export isn't encrypted.
+func newExportReader(r io.Reader, cfg *config) (*exportReader, error) {
+	er := &exportReader{
+		r:   bufio.NewReader(r),
+		sum: crc32.NewIEEE(),
+		cfg: cfg,
+	}
+
+	// read the magic number and version.
+	hdr := make([]byte, len(exportMagic)+1)
+
+	_, err := io.ReadFull(er, hdr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotExport, err)
+	}
+
+	if string(hdr[:len(exportMagic)]) != exportMagic {
+		return nil, ErrNotExport
+	}
+
+	er.version = int(hdr[len(exportMagic)])
+
+	if er.version < 1 || er.version > exportVersion {
+		return nil, fmt.Errorf("%w: %d", ErrExportVersion, er.version)
+	}
+
+	// read the flags, the buckets can only be read if every flag is known.
+	if er.version >= 3 {
+		b, err := er.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNotExport, err)
+		}
+
+		er.flags = ExportFlags(b)
+
+		if er.flags&^knownExportFlags != 0 {
+			return nil, fmt.Errorf("%w: %#x", ErrExportFlags, b)
+		}
+
+		if er.flags&ExportEncrypted != 0 && (cfg == nil || cfg.aead == nil) {
+			return nil, fmt.Errorf("%w: the export's buckets are encrypted", ErrEncrypted)
+		}
+	}
+
+	// read the creation time.
+	if er.version >= 2 {
+		ms, err := binary.ReadVarint(er)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNotExport, err)
+		}
+
+		er.created = time.UnixMilli(ms)
+	}
+
+	// read the number of buckets.
+	er.left, err = binary.ReadUvarint(er)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotExport, err)
+	}
+
+	return er, nil
+}
+
+// read the next bucket, returning `io.EOF` once every bucket is read and the checksum is verified.
+func (er *exportReader) next() (tempdb.Bucket, error) {
+	if er.left == 0 {
+		err := er.verify()
+		if err != nil {
+			return tempdb.Bucket{}, err
+		}
+
+		return tempdb.Bucket{}, io.EOF
+	}
+
+	er.left--
+
+	// read the length of the bucket.
+	n, err := binary.ReadUvarint(er)
+	if err != nil {
+		return tempdb.Bucket{}, io.ErrUnexpectedEOF
+	}
+
+	if n > maxExportBucket {
+		return tempdb.Bucket{}, fmt.Errorf("%w: bucket of %d bytes", ErrNotExport, n)
+	}
+
+	// read the bucket as it arrives, so a truncated export doesn't allocate its length.
+	buf := new(bytes.Buffer)
+
+	_, err = io.CopyN(buf, er, int64(n))
+	if err != nil {
+		return tempdb.Bucket{}, io.ErrUnexpectedEOF
+	}
+
+	v := buf.Bytes()
+
+	if er.flags&ExportEncrypted != 0 {
+		v, err = er.cfg.decrypt(v)
+		if err != nil {
+			return tempdb.Bucket{}, err
+		}
+	}
+
+	if er.flags&ExportCompressed != 0 {
+		v, err = decompress(v)
+		if err != nil {
+			return tempdb.Bucket{}, err
+		}
+	}
+
+	return decode(v)
+}
+
+// verify the checksum at the end of the export.
+func (er *exportReader) verify() error {
+	// read the checksum without adding it to the sum.
+	v := make([]byte, crc32.Size)
+
+	_, err := io.ReadFull(er.r, v)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	if binary.BigEndian.Uint32(v) != er.sum.Sum32() {
+		return ErrExportChecksum
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+// create a database with a nested bucket.
+func populated(t *testing.T, nm string) walletdb.DB {
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put([]byte("a"), []byte("1"))
+		if err != nil {
+			return err
+		}
+
+		nbkt, err := bkt.CreateBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+
+		return nbkt.Put([]byte("b"), []byte("2"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// read every bucket in an export.
+func readAll(data []byte) ([]tempdb.Bucket, error) {
+	er, err := newExportReader(bytes.NewReader(data), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var bkts []tempdb.Bucket
+
+	for {
+		bkt, err := er.next()
+		if err == io.EOF {
+			return bkts, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		bkts = append(bkts, bkt)
+	}
+}
+
+func TestExportTo(t *testing.T) {
+	db := populated(t, "export.db")
+
+	// pin the creation time, so every export is equal.
+	created := time.UnixMilli(1_700_000_000_000)
+
+	db.(*DB).cfg.now = func() time.Time { return created }
+
+	buf := new(bytes.Buffer)
+
+	err := db.(*DB).ExportTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bkts, err := readAll(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure every bucket was exported.
+	state := db.(*DB).State.Buckets
+	if len(bkts) != len(state) {
+		t.Fatalf("expected %d buckets: got %d", len(state), len(bkts))
+	}
+
+	for i := range bkts {
+		if !equal(&bkts[i], &state[i]) {
+			t.Fatalf("expected bucket %v: got %v", state[i], bkts[i])
+		}
+	}
+
+	// ensure Export matches the stream.
+	data, err := db.(*DB).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("expected Export to match ExportTo")
+	}
+
+	// flip a byte in the last bucket, so the checksum doesn't match.
+	data[len(data)-crc32.Size-1] ^= 0xff
+
+	_, err = readAll(data)
+	if err == nil {
+		t.Fatal("expected a corrupt export to fail")
+	}
+
+	// ensure data that isn't an export is rejected.
+	_, err = readAll([]byte("not an export"))
+	if !errors.Is(err, ErrNotExport) {
+		t.Fatalf("expected %v: got %v", ErrNotExport, err)
+	}
+}
+
+func TestVerifyExport(t *testing.T) {
+	db := populated(t, "verify.db")
+
+	start := time.Now().Truncate(time.Millisecond)
+
+	data, err := db.(*DB).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := VerifyExport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Version != exportVersion {
+		t.Fatalf("expected version %d: got %d", exportVersion, info.Version)
+	}
+
+	if n := len(db.(*DB).State.Buckets); info.Buckets != n {
+		t.Fatalf("expected %d buckets: got %d", n, info.Buckets)
+	}
+
+	if info.Created.Before(start) || info.Created.After(time.Now()) {
+		t.Fatalf("expected the export to be created after %v: got %v", start, info.Created)
+	}
+
+	// ensure a truncated export is rejected.
+	_, err = VerifyExport(data[:len(data)-crc32.Size-1])
+	if err == nil {
+		t.Fatal("expected a truncated export to fail")
+	}
+
+	// an export with 1 bucket of n bytes, without the bucket.
+	header := func(n uint64) []byte {
+		v := append([]byte(exportMagic), exportVersion, 0)
+		v = binary.AppendVarint(v, 0)
+		v = binary.AppendUvarint(v, 1)
+
+		return binary.AppendUvarint(v, n)
+	}
+
+	// ensure a bucket longer than any record is rejected.
+	_, err = VerifyExport(header(1 << 62))
+	if !errors.Is(err, ErrNotExport) {
+		t.Fatalf("expected %v: got %v", ErrNotExport, err)
+	}
+
+	// ensure a bucket longer than the export is rejected.
+	_, err = VerifyExport(header(maxExportBucket))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected %v: got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestExportHeader(t *testing.T) {
+	db := populated(t, "header.db")
+
+	data, err := db.(*DB).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the magic number, version and flags start the export.
+	hdr := append([]byte(exportMagic), exportVersion, 0)
+
+	if !bytes.HasPrefix(data, hdr) {
+		t.Fatalf("expected the export to start with %x: got %x", hdr, data[:len(hdr)])
+	}
+
+	info, err := VerifyExport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Version != exportVersion {
+		t.Fatalf("expected version %d: got %d", exportVersion, info.Version)
+	}
+
+	// ensure a bad magic number is rejected.
+	bad := bytes.Clone(data)
+	bad[0] ^= 0xff
+
+	_, err = VerifyExport(bad)
+	if !errors.Is(err, ErrNotExport) {
+		t.Fatalf("expected %v: got %v", ErrNotExport, err)
+	}
+
+	// ensure an unknown flag is rejected.
+	bad = bytes.Clone(data)
+	bad[len(exportMagic)+1] = 0x80
+
+	_, err = VerifyExport(bad)
+	if !errors.Is(err, ErrExportFlags) {
+		t.Fatalf("expected %v: got %v", ErrExportFlags, err)
+	}
+}
@@ -0,0 +1,434 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/linden/tempdb"
+)
+
+// stop flushing the named top-level buckets on commit.
+// changes to them stay in memory until they are flushed with `Flush`.
+func (db *DB) Defer(names ...[]byte) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for _, nm := range names {
+		db.deferred[string(nm)] = true
+	}
+}
+
+// write the named deferred top-level buckets, or every deferred bucket with pending changes when no names are given.
+// the buckets are written in a single write to the backend.
+func (db *DB) Flush(names ...[]byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	defer db.measure(&db.stats.Flush)()
+
+	// default to every bucket with pending changes.
+	if len(names) == 0 {
+		names = db.pending()
+	}
+
+	// the top-level buckets to flush.
+	flush := make(map[string]bool)
+
+	for _, nm := range names {
+		// skip buckets without changes, they are already stored.
+		if db.dirty[string(nm)] {
+			flush[string(nm)] = true
+		}
+	}
+
+	var puts []tempdb.Bucket
+	var dels []tempdb.BucketID
+
+	// find the top-level bucket of every bucket.
+	rts := roots(db.State.Buckets)
+
+	// write every bucket in the flushed top-level buckets.
+	for _, bkt := range db.State.Buckets {
+		if flush[rts[bkt.ID]] {
+			puts = append(puts, bkt)
+		}
+	}
+
+	// delete the stored records of buckets that no longer exist.
+	for id, rt := range db.records {
+		if _, ok := rts[owner(id)]; !ok && flush[rt] {
+			dels = append(dels, id)
+		}
+	}
+
+	err := db.write(puts, dels)
+	if err != nil {
+		return err
+	}
+
+	for nm := range flush {
+		delete(db.dirty, nm)
+	}
+
+	return nil
+}
+
+// call the function when a flush in the background fails, such as an idle flush or an asynchronous update, since
+// there's nothing to return the error to. the error is an `ErrNotFlushed` error and the changes stay pending, so the app can alert the user or retry with `Flush`.
+func (db *DB) SetFlushErrorHandler(fn func(error)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.onFlushError = fn
+}
+
+// flush in the background, passing any error to the handler.
+func (db *DB) flushBackground(names ...[]byte) {
+	err := db.Flush(names...)
+	if err == nil {
+		return
+	}
+
+	db.lock.Lock()
+	fn := db.onFlushError
+	db.lock.Unlock()
+
+	if fn != nil {
+		fn(fmt.Errorf("%w: %w", ErrNotFlushed, err))
+	}
+}
+
+// rewrite the whole database from memory, ignoring what's tracked as stored, for when storage is suspected to have
+// drifted from the database. every stored record is deleted and every bucket is put in a single write, unless a batch
+// size is set. pending changes are written too.
+func (db *DB) ForceRewrite() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// find what's actually stored.
+	recs, err := db.cfg.load(db.backend)
+	if err != nil {
+		return err
+	}
+
+	dels := make([]tempdb.BucketID, 0, len(recs.Buckets))
+
+	for id := range recs.Buckets {
+		dels = append(dels, id)
+	}
+
+	slices.Sort(dels)
+
+	// track the stored records, so the count is right and nothing is skipped as unchanged.
+	clear(db.records)
+	clear(db.hashes)
+
+	for _, id := range dels {
+		db.records[id] = ""
+	}
+
+	err = db.write(db.State.Buckets, dels)
+	if err != nil {
+		return err
+	}
+
+	clear(db.dirty)
+
+	return nil
+}
+
+// get the top-level buckets with changes that have not been flushed, deferred or from a failed write.
+func (db *DB) Pending() [][]byte {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.pending()
+}
+
+// check if any deferred bucket has changes that have not been flushed, such as to warn before the page is closed.
+// changes to other buckets are written on commit, so they are only pending when the write failed, see `ErrNotFlushed`.
+func (db *DB) HasPendingChanges() bool {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return len(db.dirty) > 0
+}
+
+func (db *DB) pending() [][]byte {
+	var names [][]byte
+
+	for nm := range db.dirty {
+		names = append(names, []byte(nm))
+	}
+
+	// sort the names so the order is stable.
+	slices.SortFunc(names, bytes.Compare)
+
+	return names
+}
+
+// flush the buckets a transaction changed, skipping deferred buckets.
+func (db *DB) commit(prev []tempdb.Bucket, opts UpdateOptions) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	defer db.measure(&db.stats.Update)()
+
+	// delete the buckets nested in deleted buckets, tempdb only deletes the bucket itself.
+	db.State.Buckets = prune(db.State.Buckets)
+
+	// the state after the transaction.
+	next := db.State.Buckets
+
+	// index the buckets from before the transaction.
+	old := make(map[tempdb.BucketID]*tempdb.Bucket)
+
+	for i := range prev {
+		old[prev[i].ID] = &prev[i]
+	}
+
+	var puts []tempdb.Bucket
+	var dels []tempdb.BucketID
+
+	// find the top-level bucket of every bucket, before and after.
+	prts := roots(prev)
+	nrts := roots(next)
+
+	// the top-level buckets which changed, for the syncer.
+	changed := make(map[string]bool)
+
+	// the top-level buckets to flush in the background, see `UpdateOptions.Async`.
+	async := make(map[string]bool)
+
+	// check if a top-level bucket's changes are written later instead of on commit.
+	later := func(rt string) bool {
+		if opts.Immediate {
+			return false
+		}
+
+		if db.deferred[rt] || db.cfg.idle > 0 {
+			return true
+		}
+
+		if opts.Async {
+			async[rt] = true
+		}
+
+		return opts.Async
+	}
+
+	for i, bkt := range next {
+		o, ok := old[bkt.ID]
+
+		// remove the bucket, so only deleted buckets remain.
+		delete(old, bkt.ID)
+
+		// skip buckets that did not change.
+		if ok && equal(o, &next[i]) {
+			continue
+		}
+
+		// update the counts.
+		if ok {
+			db.keys -= entries(o)
+		} else {
+			db.buckets++
+		}
+
+		db.keys += entries(&next[i])
+
+		changed[nrts[bkt.ID]] = true
+
+		// mark deferred buckets as dirty instead of writing them.
+		if rt := nrts[bkt.ID]; later(rt) {
+			db.dirty[rt] = true
+			continue
+		}
+
+		puts = append(puts, bkt)
+	}
+
+	// the remaining buckets were deleted.
+	for id, o := range old {
+		db.buckets--
+		db.keys -= entries(o)
+
+		rt, ok := prts[id]
+
+		// orphans stored by older versions belong to no top-level bucket, delete them with the commit.
+		if !ok {
+			dels = append(dels, id)
+			continue
+		}
+
+		changed[rt] = true
+
+		if later(rt) {
+			db.dirty[rt] = true
+			continue
+		}
+
+		dels = append(dels, id)
+	}
+
+	// flush every change once the database is idle.
+	if db.cfg.idle > 0 && len(db.dirty) > 0 {
+		db.scheduleIdle()
+	}
+
+	// flush the asynchronous changes once the commit returns, `Flush` waits for the lock.
+	if len(async) > 0 {
+		names := make([][]byte, 0, len(async))
+
+		for nm := range async {
+			names = append(names, []byte(nm))
+		}
+
+		go db.flushBackground(names...)
+	}
+
+	if db.cfg.timed() {
+		db.touch(changed)
+		db.bury(changed, next)
+	}
+
+	// record the changes, whether or not they're flushed.
+	if db.changeLog != nil {
+		err := db.record(changed)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := db.write(puts, dels)
+	if err != nil {
+		// keep the changes pending, so the next flush retries them.
+		for _, bkt := range puts {
+			db.dirty[nrts[bkt.ID]] = true
+		}
+
+		for _, id := range dels {
+			db.dirty[prts[id]] = true
+		}
+
+		return fmt.Errorf("%w: %w", ErrNotFlushed, err)
+	}
+
+	// write a snapshot once the commit is stored.
+	if db.cfg.backupKeep > 0 {
+		err = db.backup()
+		if err != nil {
+			return err
+		}
+	}
+
+	// push the changes once they're stored.
+	if db.cfg.syncer != nil {
+		return db.push(changed)
+	}
+
+	return nil
+}
+
+// find the top-level bucket key of every bucket, by bucket ID. orphans, whose parents don't exist, have none.
+func roots(bkts []tempdb.Bucket) map[tempdb.BucketID]string {
+	// index the buckets.
+	byID := make(map[tempdb.BucketID]*tempdb.Bucket)
+
+	for i := range bkts {
+		byID[bkts[i].ID] = &bkts[i]
+	}
+
+	rts := make(map[tempdb.BucketID]string)
+
+	for _, bkt := range bkts {
+		// walk up the parents until we reach a top-level bucket.
+		rt := byID[bkt.ID]
+
+		for rt != nil && rt.Parent != tempdb.RootBucketID {
+			rt = byID[rt.Parent]
+		}
+
+		if rt != nil {
+			rts[bkt.ID] = string(rt.Key)
+		}
+	}
+
+	return rts
+}
+
+// remove the orphaned buckets, such as the buckets nested in a deleted bucket.
+func prune(bkts []tempdb.Bucket) []tempdb.Bucket {
+	rts := roots(bkts)
+
+	if len(rts) == len(bkts) {
+		return bkts
+	}
+
+	return slices.DeleteFunc(slices.Clone(bkts), func(bkt tempdb.Bucket) bool {
+		_, ok := rts[bkt.ID]
+		return !ok
+	})
+}
+
+// find the path of every bucket, by bucket ID. keys that aren't printable are hex encoded.
+func paths(bkts []tempdb.Bucket) map[tempdb.BucketID]string {
+	// index the buckets.
+	byID := make(map[tempdb.BucketID]*tempdb.Bucket)
+
+	for i := range bkts {
+		byID[bkts[i].ID] = &bkts[i]
+	}
+
+	pths := make(map[tempdb.BucketID]string)
+
+	for _, bkt := range bkts {
+		var parts []string
+
+		// walk up the parents, adding every key.
+		for b, ok := byID[bkt.ID]; ok; b, ok = byID[b.Parent] {
+			parts = append(parts, printable(b.Key))
+
+			if b.Parent == tempdb.RootBucketID {
+				break
+			}
+		}
+
+		slices.Reverse(parts)
+		pths[bkt.ID] = strings.Join(parts, "/")
+	}
+
+	return pths
+}
+
+// format a key to be read, hex encoding it if it isn't printable.
+func printable(key []byte) string {
+	if utf8.Valid(key) && !bytes.ContainsFunc(key, func(r rune) bool { return !unicode.IsPrint(r) || r == '/' }) {
+		return string(key)
+	}
+
+	return "0x" + hex.EncodeToString(key)
+}
+
+// check if two buckets have the same contents.
+func equal(a, b *tempdb.Bucket) bool {
+	if a.Parent != b.Parent || !bytes.Equal(a.Key, b.Key) || len(a.Value) != len(b.Value) {
+		return false
+	}
+
+	for k, v := range a.Value {
+		bv, ok := b.Value[k]
+		if !ok || !bytes.Equal(v, bv) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,485 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+func TestFlush(t *testing.T) {
+	// the name of the database.
+	nm := "flush.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the names of the buckets.
+	keychain := []byte("keychain")
+	cache := []byte("cache")
+
+	// defer the cache, the keychain is flushed on every commit.
+	db.(*DB).Defer(cache)
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, nm := range [][]byte{keychain, cache} {
+			bkt, err := tx.CreateTopLevelBucket(nm)
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put([]byte("key"), nm)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure only the cache is pending.
+	pnd := db.(*DB).Pending()
+	if len(pnd) != 1 || !bytes.Equal(pnd[0], cache) {
+		t.Fatalf("expected only %s to be pending: got %s", cache, pnd)
+	}
+
+	// check which buckets are stored.
+	stored := func() (bool, bool) {
+		sdb, err := walletdb.Open("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var kc, ch bool
+
+		err = walletdb.View(sdb, func(tx walletdb.ReadTx) error {
+			kc = tx.ReadBucket(keychain) != nil
+			ch = tx.ReadBucket(cache) != nil
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return kc, ch
+	}
+
+	kc, ch := stored()
+	if !kc || ch {
+		t.Fatalf("expected only the keychain to be stored: keychain %t, cache %t", kc, ch)
+	}
+
+	// flush the cache.
+	err = db.(*DB).Flush(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pnd := db.(*DB).Pending(); len(pnd) != 0 {
+		t.Fatalf("expected nothing to be pending: got %s", pnd)
+	}
+
+	kc, ch = stored()
+	if !kc || !ch {
+		t.Fatalf("expected both buckets to be stored: keychain %t, cache %t", kc, ch)
+	}
+}
+
+func TestHasPendingChanges(t *testing.T) {
+	db, err := walletdb.Create("localdb", "pending.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := []byte("cache")
+	db.(*DB).Defer(cache)
+
+	// put a value in the deferred bucket.
+	put := func(v []byte) {
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt := tx.ReadWriteBucket(cache)
+			if bkt == nil {
+				var err error
+
+				bkt, err = tx.CreateTopLevelBucket(cache)
+				if err != nil {
+					return err
+				}
+			}
+
+			return bkt.Put([]byte("key"), v)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if db.(*DB).HasPendingChanges() {
+		t.Fatal("expected no pending changes in a new database")
+	}
+
+	put([]byte("a"))
+
+	if !db.(*DB).HasPendingChanges() {
+		t.Fatal("expected pending changes after a put")
+	}
+
+	err = db.(*DB).Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db.(*DB).HasPendingChanges() {
+		t.Fatal("expected no pending changes after a flush")
+	}
+
+	put([]byte("b"))
+
+	if !db.(*DB).HasPendingChanges() {
+		t.Fatal("expected pending changes after a put")
+	}
+}
+
+func TestUnchangedBuckets(t *testing.T) {
+	var cb *countingBackend
+
+	db, err := walletdb.Create("localdb", "unchanged.db", WithBackend(counting(&cb)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := []byte("cache")
+	db.(*DB).Defer(cache)
+
+	// put a value in a nested bucket of the cache.
+	put := func(nm, v string) {
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt := tx.ReadWriteBucket(cache)
+			if bkt == nil {
+				var err error
+
+				bkt, err = tx.CreateTopLevelBucket(cache)
+				if err != nil {
+					return err
+				}
+			}
+
+			nbkt, err := bkt.CreateBucketIfNotExists([]byte(nm))
+			if err != nil {
+				return err
+			}
+
+			return nbkt.Put([]byte("key"), []byte(v))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put("a", "1")
+	put("b", "1")
+
+	err = db.(*DB).Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cache and both nested buckets are written.
+	if cb.puts != 3 {
+		t.Fatalf("expected 3 puts: got %d", cb.puts)
+	}
+
+	// change 1 nested bucket, the flush rewrites the whole cache but only the changed bucket is put.
+	put("a", "2")
+
+	err = db.(*DB).Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cb.puts != 4 {
+		t.Fatalf("expected 4 puts: got %d", cb.puts)
+	}
+
+	// read a value and write it back, nothing should be written.
+	writes := cb.writes
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt := tx.ReadWriteBucket(cache).NestedReadWriteBucket([]byte("b"))
+		return bkt.Put([]byte("key"), bkt.Get([]byte("key")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.(*DB).Flush(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cb.writes != writes {
+		t.Fatalf("expected no writes: got %d", cb.writes-writes)
+	}
+}
+
+func TestFlushFailure(t *testing.T) {
+	// the name of the database.
+	nm := "failure.db"
+
+	var cb *crashingBackend
+
+	// a backend which fails every write while the quota is exceeded.
+	full := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		cb = &crashingBackend{Backend: b, after: math.MaxInt}
+		return cb, exist, nil
+	}
+
+	db, err := walletdb.Create("localdb", nm, WithBackend(full))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// exceed the quota.
+	cb.after = cb.writes
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("value"))
+	})
+	if !errors.Is(err, ErrNotFlushed) {
+		t.Fatalf("expected %v: got %v", ErrNotFlushed, err)
+	}
+
+	// ensure the change is kept in memory, pending a flush.
+	if !db.(*DB).HasPendingChanges() {
+		t.Fatal("expected the change to be pending")
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if v := tx.ReadBucket([]byte("bucket")).Get([]byte("key")); string(v) != "value" {
+			t.Fatalf("expected value: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// free space and retry.
+	cb.after = math.MaxInt
+
+	err = db.(*DB).Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db.(*DB).HasPendingChanges() {
+		t.Fatal("expected nothing to be pending")
+	}
+
+	// ensure the change was stored.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if v := tx.ReadBucket([]byte("bucket")).Get([]byte("key")); string(v) != "value" {
+			t.Fatalf("expected value: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForceRewrite(t *testing.T) {
+	nm := "rewrite.db"
+
+	db := populated(t, nm)
+
+	// desync the storage, deleting a record and storing one the database doesn't know about.
+	id, _ := topLevel(db.(*DB).State.Buckets, []byte("parent"))
+
+	err := db.(*DB).backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{1000: []byte("stray")},
+		},
+		Deletes: []tempdb.BucketID{id},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.(*DB).ForceRewrite()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure the database opens with every bucket and without the stray record.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := recs.Buckets[1000]; ok {
+		t.Fatal("expected the stray record to be deleted")
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("parent"))
+		if bkt == nil || string(bkt.Get([]byte("a"))) != "1" {
+			t.Fatal("expected the deleted record to be rewritten")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateWith(t *testing.T) {
+	db, err := walletdb.Create("localdb", "update-with.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ldb := db.(*DB)
+
+	// the names of the buckets.
+	balance := []byte("balance")
+	ui := []byte("ui")
+
+	// defer the balance, so only an immediate update flushes it on commit.
+	ldb.Defer(balance)
+
+	put := func(nm []byte, opts UpdateOptions) {
+		err := ldb.UpdateWith(opts, func(tx walletdb.ReadWriteTx) error {
+			bkt, err := tx.CreateTopLevelBucket(nm)
+			if err != nil {
+				return err
+			}
+
+			return bkt.Put([]byte("key"), []byte("value"))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put(balance, UpdateOptions{Immediate: true})
+
+	if ldb.HasPendingChanges() {
+		t.Fatalf("expected the immediate update to be flushed: got %s pending", ldb.Pending())
+	}
+
+	put(ui, UpdateOptions{Async: true})
+
+	// wait for the background flush.
+	for i := 0; ldb.HasPendingChanges(); i++ {
+		if i == 100 {
+			t.Fatalf("expected the asynchronous update to be flushed: got %s pending", ldb.Pending())
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// ensure both buckets are stored.
+	recs, err := ldb.backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recs.Buckets) != 2 {
+		t.Fatalf("expected 2 stored buckets: got %d", len(recs.Buckets))
+	}
+}
+
+func TestFlushErrorHandler(t *testing.T) {
+	var cb *crashingBackend
+
+	// a backend which fails every write while the quota is exceeded.
+	full := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		cb = &crashingBackend{Backend: b, after: math.MaxInt}
+		return cb, exist, nil
+	}
+
+	db, err := walletdb.Create("localdb", "flush-error.db", WithBackend(full))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ldb := db.(*DB)
+
+	errs := make(chan error, 1)
+
+	ldb.SetFlushErrorHandler(func(err error) {
+		errs <- err
+	})
+
+	// exceed the quota.
+	cb.after = cb.writes
+
+	err = ldb.UpdateWith(UpdateOptions{Async: true}, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrNotFlushed) {
+			t.Fatalf("expected %v: got %v", ErrNotFlushed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the handler to be called")
+	}
+
+	// ensure the change is still pending.
+	if !ldb.HasPendingChanges() {
+		t.Fatal("expected the change to be pending")
+	}
+}
@@ -0,0 +1,73 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// call fn with every key and value in every bucket, including nested buckets, along with the path of the bucket
+// starting with its top-level bucket. buckets are walked in order of their path and keys in order, nested buckets are
+// walked after their parent's keys rather than passed as keys. iterating stops at the first error fn returns.
+// the path, key and value must not be modified, and are only valid until fn returns.
+func (db *DB) ForEachKey(fn func(bucketPath [][]byte, key, value []byte) error) error {
+	tx, err := db.BeginReadTx()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// group the buckets by parent, sorted by key.
+	children := make(map[tempdb.BucketID][]tempdb.Bucket)
+
+	for _, bkt := range tx.(*tempdb.Transaction).State.Buckets {
+		children[bkt.Parent] = append(children[bkt.Parent], bkt)
+	}
+
+	for _, bkts := range children {
+		slices.SortFunc(bkts, func(a, b tempdb.Bucket) int {
+			return bytes.Compare(a.Key, b.Key)
+		})
+	}
+
+	var walk func(id tempdb.BucketID, path [][]byte) error
+
+	walk = func(id tempdb.BucketID, path [][]byte) error {
+		for _, bkt := range children[id] {
+			pth := append(slices.Clone(path), bkt.Key)
+
+			keys := make([]string, 0, len(bkt.Value))
+
+			for k := range bkt.Value {
+				// skip the keys of nested buckets.
+				if slices.ContainsFunc(children[bkt.ID], func(c tempdb.Bucket) bool { return string(c.Key) == k }) {
+					continue
+				}
+
+				keys = append(keys, k)
+			}
+
+			slices.Sort(keys)
+
+			for _, k := range keys {
+				err := fn(pth, []byte(k), bkt.Value[k])
+				if err != nil {
+					return err
+				}
+			}
+
+			err := walk(bkt.ID, pth)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(tempdb.RootBucketID, nil)
+}
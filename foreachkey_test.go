@@ -0,0 +1,92 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestForEachKey(t *testing.T) {
+	db, err := walletdb.Create("localdb", "for-each-key.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		wlt, err := tx.CreateTopLevelBucket([]byte("wallet"))
+		if err != nil {
+			return err
+		}
+
+		err = wlt.Put([]byte("version"), []byte("1"))
+		if err != nil {
+			return err
+		}
+
+		accts, err := wlt.CreateBucket([]byte("accounts"))
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"savings", "default"} {
+			err = accts.Put([]byte(k), []byte(k))
+			if err != nil {
+				return err
+			}
+		}
+
+		cache, err := tx.CreateTopLevelBucket([]byte("cache"))
+		if err != nil {
+			return err
+		}
+
+		return cache.Put([]byte("height"), []byte("100"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type pair struct {
+		path       string
+		key, value string
+	}
+
+	var pairs []pair
+
+	err = db.(*DB).ForEachKey(func(path [][]byte, key, value []byte) error {
+		pairs = append(pairs, pair{string(bytes.Join(path, []byte("/"))), string(key), string(value)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []pair{
+		{"cache", "height", "100"},
+		{"wallet", "version", "1"},
+		{"wallet/accounts", "default", "default"},
+		{"wallet/accounts", "savings", "savings"},
+	}
+
+	if !reflect.DeepEqual(pairs, exp) {
+		t.Fatalf("expected %v: got %v", exp, pairs)
+	}
+
+	// ensure an error stops iterating.
+	errStop := errors.New("stop")
+
+	var n int
+
+	err = db.(*DB).ForEachKey(func(path [][]byte, key, value []byte) error {
+		n++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || n != 1 {
+		t.Fatalf("expected to stop after 1 pair with %v: got %d pairs and %v", errStop, n, err)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build js && wasm
+
+package localdb
+
+import "sync"
+
+var (
+	// the databases opened with `WithSharedHandle`, by path.
+	handles = make(map[string]*DB)
+
+	// guards the handles.
+	handlesLock sync.Mutex
+)
+
+// share a single handle between every `Open` of the database in the page, instead of opening another connection with
+// its own state that would overwrite the other's changes. every `Open` must be paired with a `Close`, the database is
+// closed once the last handle is. later opens get the handle with the options it was first opened with.
+func WithSharedHandle() Option {
+	return func(cfg *config) {
+		cfg.sharedHandle = true
+	}
+}
+
+// get the open handle of the database, if it's shared.
+func sharedHandle(args []any) *DB {
+	if len(args) == 0 {
+		return nil
+	}
+
+	path, ok := args[0].(string)
+	if !ok {
+		return nil
+	}
+
+	opts, err := parseOptions(args[1:])
+	if err != nil || !newConfig(opts...).sharedHandle {
+		return nil
+	}
+
+	handlesLock.Lock()
+	defer handlesLock.Unlock()
+
+	db, ok := handles[path]
+	if !ok {
+		return nil
+	}
+
+	db.refs++
+
+	return db
+}
+
+// share the handle of an opened database, if it's configured.
+func (db *DB) share() {
+	if !db.cfg.sharedHandle {
+		return
+	}
+
+	handlesLock.Lock()
+	defer handlesLock.Unlock()
+
+	db.refs = 1
+	handles[db.Path] = db
+}
+
+// release a handle, reporting whether it was the last one so the database should be closed.
+func (db *DB) release() bool {
+	if !db.cfg.sharedHandle {
+		return true
+	}
+
+	handlesLock.Lock()
+	defer handlesLock.Unlock()
+
+	db.refs--
+
+	if db.refs > 0 {
+		return false
+	}
+
+	delete(handles, db.Path)
+
+	return true
+}
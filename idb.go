@@ -0,0 +1,612 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"syscall/js"
+
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+const (
+	// the name of the object store for the buckets.
+	bucketStore = "buckets"
+
+	// the name of the object store for the metadata.
+	metaStore = "metadata"
+
+	// the name of the object store for the bucket index.
+	indexStore = "bucket_index"
+
+	// the name of the object store for the snapshots, see `WithAutoBackup`.
+	backupStore = "backups"
+
+	// the name of the object store for the keys of every bucket, see `WithKeyIndex`.
+	keyStore = "key_index"
+
+	// the name of the object store for the records of a flush before they're promoted, see `WithTwoPhaseFlush`.
+	stagingStore = "staging"
+
+	// the version of localdb's stores.
+	version = 6
+)
+
+var (
+	// the browser does not support listing indexeddb databases.
+	ErrListUnsupported = errors.New("listing databases is not supported")
+
+	// the database isn't stored in indexeddb.
+	ErrNotIndexedDB = errors.New("database is not stored in indexeddb")
+
+	// the indexeddb database is missing one of localdb's object stores, it was likely created by another tool.
+	ErrMissingStore = errors.New("object store is missing")
+)
+
+// Ensure `idbBackend` complies with the `Backend` interface.
+var _ Backend = (*idbBackend)(nil)
+
+type idbBackend struct {
+	idb *indexeddb.DB
+
+	// the name of the indexeddb database.
+	name string
+
+	// the prefix of localdb's store names, for databases sharing an indexeddb database, see `Shared`.
+	prefix string
+
+	// counts the requests, see `WithRoundTrips`.
+	trips *RoundTrips
+
+	// the in-flight load's transaction, so it can be aborted, see `WithOpenTimeout`.
+	loading *indexeddb.Transaction
+
+	// whether the database was deleted while it was open, the function handling it and the callback, see
+	// `WithOnDeleted`.
+	deleted   bool
+	gone      js.Func
+	onDeleted func()
+}
+
+func (b *idbBackend) countTrips(t *RoundTrips) {
+	b.trips = t
+}
+
+// get the name of one of localdb's stores.
+func (b *idbBackend) store(name string) string {
+	return b.prefix + name
+}
+
+func (b *idbBackend) Load() (*Records, error) {
+	if b.deleted {
+		return nil, ErrDatabaseDeleted
+	}
+
+	// create a read transaction.
+	itx, err := b.idb.NewTransaction([]string{b.store(bucketStore), b.store(metaStore)}, indexeddb.ReadMode)
+	if err != nil {
+		return nil, err
+	}
+
+	b.trips.transaction()
+
+	b.loading = itx
+	defer func() { b.loading = nil }()
+
+	recs := &Records{
+		Buckets: make(map[tempdb.BucketID][]byte),
+		Meta:    make(map[string][]byte),
+	}
+
+	// get every metadata value, first so we know how the buckets are stored.
+	err = b.load(itx.Store(b.store(metaStore)), false, func(key js.Value, val []byte) {
+		recs.Meta[key.String()] = val
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// get every bucket.
+	err = b.load(itx.Store(b.store(bucketStore)), isRaw(recs.Meta), func(key js.Value, val []byte) {
+		recs.Buckets[tempdb.BucketID(key.Int())] = val
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+func (b *idbBackend) Write(ch *Changes) error {
+	return b.write(ch, false)
+}
+
+// write the changes in a single transaction, moving every staged record into the bucket store if promote is set.
+func (b *idbBackend) write(ch *Changes, promote bool) error {
+	if b.deleted {
+		return ErrDatabaseDeleted
+	}
+
+	names := []string{b.store(bucketStore), b.store(metaStore), b.store(indexStore), b.store(keyStore)}
+
+	if promote {
+		names = append(names, b.store(stagingStore))
+	}
+
+	// create a new read/write transaction.
+	itx, err := b.idb.NewTransaction(names, indexeddb.ReadWriteMode)
+	if err != nil {
+		return err
+	}
+
+	b.trips.transaction()
+
+	// open the bucket store.
+	bkts := itx.Store(b.store(bucketStore))
+
+	// open the bucket index store.
+	idx := itx.Store(b.store(indexStore))
+
+	// open the key index store.
+	keys := itx.Store(b.store(keyStore))
+
+	// delete the records before starting the batch, the batch must be waited on before any other request.
+	for _, id := range ch.Deletes {
+		err = bkts.Delete(uint64(id))
+		if err != nil {
+			return err
+		}
+
+		// always remove the bucket from the indexes, in case it was indexed before.
+		err = idx.Delete(uint64(id))
+		if err != nil {
+			return err
+		}
+
+		err = keys.Delete(uint64(id))
+		if err != nil {
+			return err
+		}
+
+		b.trips.delete(3)
+	}
+
+	for id, v := range ch.Keys {
+		err = keys.Put(uint64(id), quote(v))
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	// the index is stored unquoted, so it's readable from the browser's devtools.
+	for id, nm := range ch.Names {
+		err = idx.Put(uint64(id), nm)
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	// open the metadata store.
+	meta := itx.Store(b.store(metaStore))
+
+	// find how the buckets are stored, the changes set the mode when the database is created.
+	raw, err := b.raw(meta, ch)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range ch.Meta {
+		err = meta.Put(k, quote(v))
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	// take the staged records, they're already stored the way they will be.
+	staged := make(map[tempdb.BucketID]js.Value)
+
+	if promote {
+		stg := itx.Store(b.store(stagingStore))
+
+		keys, err := request(value(stg).Call("getAllKeys"))
+		if err != nil {
+			return err
+		}
+
+		vals, err := stg.GetAll()
+		if err != nil {
+			return err
+		}
+
+		b.trips.get(2)
+
+		for i, val := range vals {
+			staged[tempdb.BucketID(keys.Index(i).Int())] = val
+		}
+
+		err = stg.Clear()
+		if err != nil {
+			return err
+		}
+
+		b.trips.delete(1)
+	}
+
+	btch := bkts.Batch()
+
+	// save every bucket by ID.
+	for id, v := range ch.Buckets {
+		var sv any
+
+		if ch.Blobs[id] {
+			sv = newBlob(v)
+		} else {
+			sv, err = storeValue(id, v, raw)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = btch.Put(uint64(id), sv)
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	for id, val := range staged {
+		err = btch.Put(uint64(id), val)
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	return btch.Wait()
+}
+
+func (b *idbBackend) Close() error {
+	b.unwatch()
+
+	connected(b.name, -1)
+
+	return b.idb.Close()
+}
+
+// IndexedDB stores the database in indexeddb, using the name as the name of the indexeddb database.
+// this is the default backend.
+func IndexedDB(name string) (Backend, bool, error) {
+	return openIndexedDB(name, 0, nil)
+}
+
+// LazyIndexedDB stores the database in indexeddb like `IndexedDB`, but a new indexeddb database is only created
+// on the first write. databases which are created but never written to don't litter indexeddb.
+func LazyIndexedDB(name string) (Backend, bool, error) {
+	exist, err := idbExists(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if exist {
+		return IndexedDB(name)
+	}
+
+	return &lazyBackend{
+		name: name,
+	}, false, nil
+}
+
+// Ensure `lazyBackend` complies with the `Backend` interface.
+var _ Backend = (*lazyBackend)(nil)
+
+// an indexeddb backend that's created on the first write.
+type lazyBackend struct {
+	name string
+
+	// the backend, once it's created.
+	b Backend
+
+	// counts the requests, see `WithRoundTrips`.
+	trips *RoundTrips
+
+	// called when the database is deleted, see `WithOnDeleted`.
+	onDeleted func()
+}
+
+func (b *lazyBackend) countTrips(t *RoundTrips) {
+	b.trips = t
+}
+
+func (b *lazyBackend) Load() (*Records, error) {
+	if b.b != nil {
+		return b.b.Load()
+	}
+
+	// nothing is stored until the first write.
+	return &Records{
+		Buckets: make(map[tempdb.BucketID][]byte),
+		Meta:    make(map[string][]byte),
+	}, nil
+}
+
+func (b *lazyBackend) Write(ch *Changes) error {
+	// create the database.
+	if b.b == nil {
+		ib, _, err := IndexedDB(b.name)
+		if err != nil {
+			return err
+		}
+
+		b.b = ib
+		ib.(*idbBackend).countTrips(b.trips)
+		ib.(*idbBackend).notifyDeleted(b.onDeleted)
+	}
+
+	return b.b.Write(ch)
+}
+
+func (b *lazyBackend) Close() error {
+	if b.b == nil {
+		return nil
+	}
+
+	return b.b.Close()
+}
+
+// IndexedDBWithUpgrade stores the database in indexeddb like `IndexedDB`, calling fn when the database is upgraded
+// so the app can create its own object stores alongside localdb's. increase the version to upgrade the app's stores,
+// every stored version must be below 65536. fn is called on every upgrade, including upgrades of localdb's own stores,
+// so it should check `HasStore` before creating a store.
+//
+// once a database is opened with an app version, it must always be opened with that version or a higher one.
+// the app must not touch the "buckets", "metadata", "bucket_index", "backups", "key_index" or "staging" stores, localdb
+// keeps the database in memory and doesn't see changes made to them, so they would be overwritten or corrupt the
+// database.
+func IndexedDBWithUpgrade(appVersion int, fn func(up *indexeddb.Upgrade) error) BackendFunc {
+	return func(name string) (Backend, bool, error) {
+		return openIndexedDB(name, appVersion, fn)
+	}
+}
+
+func openIndexedDB(name string, appVersion int, fn func(up *indexeddb.Upgrade) error) (Backend, bool, error) {
+	// wether or not the database existed before calling this function.
+	exist := true
+
+	// the localdb version is in the upper bits, so either version can be increased.
+	idb, err := indexeddb.New(name, version<<16|appVersion, func(up *indexeddb.Upgrade) error {
+		// create the buckets store, it already exists when upgrading.
+		if createStore(up, bucketStore) {
+			exist = false
+		}
+
+		// create the metadata store.
+		createStore(up, metaStore)
+
+		// create the bucket index store.
+		createStore(up, indexStore)
+
+		// create the backup store.
+		createStore(up, backupStore)
+
+		// create the key index store.
+		createStore(up, keyStore)
+
+		// create the staging store.
+		createStore(up, stagingStore)
+
+		// create the app's stores.
+		if fn != nil {
+			return fn(up)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// ensure every store exists, a database at our version may not have been created by localdb.
+	for _, str := range stores {
+		if !value(idb).Get("objectStoreNames").Call("contains", str).Bool() {
+			idb.Close()
+			return nil, false, fmt.Errorf("%w: %s", ErrMissingStore, str)
+		}
+	}
+
+	b := &idbBackend{
+		idb:  idb,
+		name: name,
+	}
+
+	// detect the database being deleted while it's open.
+	b.watch()
+
+	connected(name, 1)
+
+	return b, exist, nil
+}
+
+// check if a store exists while upgrading.
+func HasStore(up *indexeddb.Upgrade, name string) bool {
+	return value(up).Get("objectStoreNames").Call("contains", name).Bool()
+}
+
+// create a raw indexeddb transaction over the app's own stores, created with `IndexedDBWithUpgrade`.
+// the transaction is separate from walletdb transactions, it's not committed or rolled back with them.
+// including localdb's own stores risks corrupting the database, see `IndexedDBWithUpgrade`.
+func (db *DB) RawTransaction(stores []string, mode indexeddb.Mode) (*indexeddb.Transaction, error) {
+	b, ok := db.backend.(*idbBackend)
+	if !ok {
+		return nil, ErrNotIndexedDB
+	}
+
+	return b.idb.NewTransaction(stores, mode)
+}
+
+// get the underlying `IDBDatabase`, for interop with other javascript libraries.
+// the connection is owned by localdb, so it must not be closed. localdb keeps the database in memory, changes made to
+// its own stores aren't seen and risk corrupting the database, see `IndexedDBWithUpgrade`.
+func (db *DB) RawDatabase() (js.Value, error) {
+	b := indexedDB(db.backend)
+	if b == nil {
+		return js.Value{}, ErrNotIndexedDB
+	}
+
+	return value(b.idb), nil
+}
+
+// list the names of every localdb database stored in indexeddb on the origin.
+// databases are recognised by their buckets store, so other indexeddb databases are skipped.
+func ListDatabases() ([]string, error) {
+	// ensure the browser supports listing databases.
+	if indexeddb.IndexedDB.Get("databases").IsUndefined() {
+		return nil, ErrListUnsupported
+	}
+
+	infos, err := await(indexeddb.IndexedDB.Call("databases"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for i := 0; i < infos.Length(); i++ {
+		name := infos.Index(i).Get("name").String()
+
+		// open the database without a version, so it isn't upgraded.
+		idb, err := request(indexeddb.IndexedDB.Call("open", name))
+		if err != nil {
+			return nil, err
+		}
+
+		// check for the buckets store.
+		ok := idb.Get("objectStoreNames").Call("contains", bucketStore).Bool()
+		idb.Call("close")
+
+		if ok {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// check if an indexeddb database exists, without creating it.
+func idbExists(name string) (bool, error) {
+	exist := true
+
+	// open the database without a version, so it isn't upgraded.
+	req := indexeddb.IndexedDB.Call("open", name)
+
+	// a database that doesn't exist is upgraded from version 0, abort the upgrade so it isn't created.
+	upgrade := js.FuncOf(func(this js.Value, args []js.Value) any {
+		exist = false
+		req.Get("transaction").Call("abort")
+		return nil
+	})
+
+	defer upgrade.Release()
+
+	req.Set("onupgradeneeded", upgrade)
+
+	idb, err := request(req)
+
+	// opening fails once the upgrade is aborted.
+	if !exist {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	idb.Call("close")
+
+	return true, nil
+}
+
+// create an object store, returning false if it already exists.
+func createStore(up *indexeddb.Upgrade, name string) (ok bool) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		// indexeddb throws a `ConstraintError` when the store already exists.
+		if _, js := r.(js.Error); !js {
+			panic(r)
+		}
+
+		ok = false
+	}()
+
+	up.CreateStore(name)
+
+	return true
+}
+
+// get every key and value in a store, the values are unquoted unless they're stored as-is.
+func (b *idbBackend) load(str *indexeddb.Store, raw bool, fn func(key js.Value, val []byte)) error {
+	// get every key, the indexeddb package only supports getting the values.
+	keys, err := request(value(str).Call("getAllKeys"))
+	if err != nil {
+		return err
+	}
+
+	// get every value, they are in the same order as the keys.
+	vals, err := str.GetAll()
+	if err != nil {
+		return err
+	}
+
+	b.trips.get(2)
+
+	// ensure every key has a value.
+	if n := keys.Length(); n != len(vals) {
+		return fmt.Errorf("expected %d values: got %d", n, len(vals))
+	}
+
+	for i, val := range vals {
+		v, err := loadValue(val, raw)
+		if err != nil {
+			return err
+		}
+
+		fn(keys.Index(i), v)
+	}
+
+	return nil
+}
+
+// quote a value, since Go strings aren't UTF-8.
+// https://go.dev/blog/strings.
+func quote(v []byte) string {
+	return strconv.Quote(string(v))
+}
+
+// unquote a stored value.
+func unquote(val js.Value) ([]byte, error) {
+	// ensure the value is a string.
+	if t := val.Type(); t != js.TypeString {
+		return nil, fmt.Errorf("expected a type of %s: got %s", js.TypeString, t)
+	}
+
+	raw, err := strconv.Unquote(val.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(raw), nil
+}
@@ -0,0 +1,397 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"syscall/js"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+func TestListDatabases(t *testing.T) {
+	// the names of the databases.
+	nms := []string{"list-a.db", "list-b.db"}
+
+	for _, nm := range nms {
+		_, err := walletdb.Create("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// create an indexeddb database which isn't a localdb database.
+	other := "list-other"
+
+	_, err := indexeddb.New(other, 1, func(up *indexeddb.Upgrade) error {
+		up.CreateStore("other")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lst, err := ListDatabases()
+	if errors.Is(err, ErrListUnsupported) {
+		t.Skip(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, nm := range nms {
+		if !slices.Contains(lst, nm) {
+			t.Fatalf("expected %s to be listed: got %v", nm, lst)
+		}
+	}
+
+	if slices.Contains(lst, other) {
+		t.Fatalf("expected %s not to be listed: got %v", other, lst)
+	}
+}
+
+func TestBucketIndex(t *testing.T) {
+	db, err := walletdb.Create("localdb", "index.db", WithBucketIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read the stored index.
+	index := func() map[tempdb.BucketID]string {
+		itx, err := db.(*DB).backend.(*idbBackend).idb.NewTransaction([]string{indexStore}, indexeddb.ReadMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		str := itx.Store(indexStore)
+
+		keys, err := request(value(str).Call("getAllKeys"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vals, err := request(value(str).Call("getAll"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		idx := make(map[tempdb.BucketID]string)
+
+		for i := 0; i < keys.Length(); i++ {
+			if v := vals.Index(i); v.Type() == js.TypeString {
+				idx[tempdb.BucketID(keys.Index(i).Int())] = v.String()
+			}
+		}
+
+		return idx
+	}
+
+	// the expected index, from the current state.
+	expected := func() map[tempdb.BucketID]string {
+		return paths(db.(*DB).State.Buckets)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		_, err = bkt.CreateBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+
+		_, err = bkt.CreateBucket([]byte{0xff})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := index()
+	if !maps.Equal(idx, expected()) {
+		t.Fatalf("expected index %v: got %v", expected(), idx)
+	}
+
+	// collect the indexed names.
+	var nms []string
+
+	for _, nm := range idx {
+		nms = append(nms, nm)
+	}
+
+	for _, nm := range []string{"parent", "parent/child", "parent/0xff"} {
+		if !slices.Contains(nms, nm) {
+			t.Fatalf("expected %s to be indexed: got %v", nm, idx)
+		}
+	}
+
+	// delete the child, it should be removed from the index.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket([]byte("parent")).DeleteNestedBucket([]byte("child"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx = index()
+	if !maps.Equal(idx, expected()) {
+		t.Fatalf("expected index %v: got %v", expected(), idx)
+	}
+
+	if len(idx) != 2 {
+		t.Fatalf("expected 2 indexed buckets: got %v", idx)
+	}
+}
+
+func TestRawTransaction(t *testing.T) {
+	// the name of the database.
+	nm := "raw.db"
+
+	// the number of times the upgrade was called.
+	var upgrades int
+
+	upgrade := func(v int) BackendFunc {
+		return IndexedDBWithUpgrade(v, func(up *indexeddb.Upgrade) error {
+			upgrades++
+
+			// create the app's store, unless an earlier upgrade already has.
+			if !HasStore(up, "app") {
+				up.CreateStore("app")
+			}
+
+			return nil
+		})
+	}
+
+	db, err := walletdb.Create("localdb", nm, WithBackend(upgrade(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// write to the app's store.
+	itx, err := db.(*DB).RawTransaction([]string{"app"}, indexeddb.ReadWriteMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = itx.Store("app").Put("key", "app value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen with a higher app version, so the upgrade is called again.
+	// the upgrade is blocked until every other connection is closed.
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = walletdb.Open("localdb", nm, WithBackend(upgrade(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if upgrades != 2 {
+		t.Fatalf("expected 2 upgrades: got %d", upgrades)
+	}
+
+	itx, err = db.(*DB).RawTransaction([]string{"app"}, indexeddb.ReadMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := itx.Store("app").Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.String() != "app value" {
+		t.Fatalf("expected app value: got %s", v.String())
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if v := tx.ReadBucket([]byte("bucket")).Get([]byte("key")); string(v) != "value" {
+			t.Fatalf("expected value: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLazyIndexedDB(t *testing.T) {
+	// the name of the database.
+	nm := "lazy.db"
+
+	db, err := walletdb.Create("localdb", nm, WithBackend(LazyIndexedDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the indexeddb database wasn't created.
+	exist, err := idbExists(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exist {
+		t.Fatal("expected the database not to be created before the first write")
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exist, err = idbExists(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !exist {
+		t.Fatal("expected the database to be created by the first write")
+	}
+
+	// ensure the database opens like any other.
+	db, err = walletdb.Open("localdb", nm, WithBackend(LazyIndexedDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if tx.ReadBucket([]byte("bucket")) == nil {
+			t.Fatal("expected the bucket to exist")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure creating an existing database fails.
+	_, err = walletdb.Create("localdb", nm, WithBackend(LazyIndexedDB))
+	if !errors.Is(err, walletdb.ErrDbExists) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbExists, err)
+	}
+}
+
+func TestMissingStore(t *testing.T) {
+	// the name of the database.
+	nm := "missing.db"
+
+	// create a database at our version, without the buckets store.
+	idb, err := indexeddb.New(nm, version<<16, func(up *indexeddb.Upgrade) error {
+		up.CreateStore("other")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idb.Close()
+
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrMissingStore) {
+		t.Fatalf("expected %v: got %v", ErrMissingStore, err)
+	}
+}
+
+func TestRawDatabase(t *testing.T) {
+	// the name of the database.
+	nm := "raw-database.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	idb, err := db.(*DB).RawDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if idb.Type() != js.TypeObject || !idb.InstanceOf(js.Global().Get("IDBDatabase")) {
+		t.Fatalf("expected an IDBDatabase: got %v", idb)
+	}
+
+	if n := idb.Get("name").String(); n != nm {
+		t.Fatalf("expected %s: got %s", nm, n)
+	}
+}
+
+func TestDeletedWhileOpen(t *testing.T) {
+	// the name of the database.
+	nm := "deleted.db"
+
+	deleted := make(chan struct{}, 1)
+
+	db, err := walletdb.Create("localdb", nm, WithOnDeleted(func() { deleted <- struct{}{} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	putValue(t, db, "bucket", "value")
+
+	// delete the database from another connection, as clearing the site's data would.
+	_, err = request(indexeddb.IndexedDB.Call("deleteDatabase", nm))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-deleted
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket([]byte("bucket")).Put([]byte("key"), []byte("changed"))
+	})
+	if !errors.Is(err, ErrDatabaseDeleted) {
+		t.Fatalf("expected %v: got %v", ErrDatabaseDeleted, err)
+	}
+
+	// ensure a close event is detected too, it's fired when the browser clears the database.
+	other, err := walletdb.Create("localdb", "closed.db", WithOnDeleted(func() { deleted <- struct{}{} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer other.Close()
+
+	b := indexedDB(other.(*DB).backend)
+	value(b.idb).Call("dispatchEvent", js.Global().Get("Event").New("close"))
+
+	<-deleted
+
+	_, err = b.Load()
+	if !errors.Is(err, ErrDatabaseDeleted) {
+		t.Fatalf("expected %v: got %v", ErrDatabaseDeleted, err)
+	}
+}
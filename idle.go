@@ -0,0 +1,50 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"syscall/js"
+	"time"
+)
+
+// defer every commit and flush once no commit has happened for the duration, so bursts of commits are written at once.
+// the flush waits for the browser to be idle with `requestIdleCallback` where it's available.
+// changes that haven't been flushed are lost if the page is closed, `Close` flushes them.
+func WithIdleFlush(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.idle = d
+	}
+}
+
+// schedule a flush once the database is idle, the lock must be held.
+func (db *DB) scheduleIdle() {
+	// restart the timer, so the flush waits for the commits to stop.
+	if db.idleTimer != nil {
+		db.idleTimer.Stop()
+	}
+
+	db.idleTimer = time.AfterFunc(db.cfg.idle, func() {
+		ric := js.Global().Get("requestIdleCallback")
+
+		// fall back to flushing from the timer.
+		if ric.IsUndefined() {
+			db.flushBackground()
+			return
+		}
+
+		var cb js.Func
+
+		cb = js.FuncOf(func(this js.Value, args []js.Value) any {
+			cb.Release()
+
+			// flush outside the callback, since it blocks.
+			go db.flushBackground()
+
+			return nil
+		})
+
+		ric.Invoke(cb, map[string]any{
+			"timeout": db.cfg.idle.Milliseconds(),
+		})
+	})
+}
@@ -0,0 +1,71 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestIdleFlush(t *testing.T) {
+	// the idle window.
+	idle := 50 * time.Millisecond
+
+	db, err := walletdb.Create("localdb", "idle.db", WithIdleFlush(idle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// commit a burst of changes.
+	for _, nm := range []string{"a", "b", "c"} {
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			_, err := tx.CreateTopLevelBucket([]byte(nm))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure nothing is flushed during the burst.
+	if !db.(*DB).HasPendingChanges() {
+		t.Fatal("expected pending changes before the idle window")
+	}
+
+	// wait for the idle window to pass.
+	deadline := time.Now().Add(idle + 2*time.Second)
+
+	for db.(*DB).HasPendingChanges() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the changes to be flushed after the idle window")
+		}
+
+		time.Sleep(idle)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure every bucket was stored.
+	db, err = walletdb.Open("localdb", "idle.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for _, nm := range []string{"a", "b", "c"} {
+			if tx.ReadBucket([]byte(nm)) == nil {
+				t.Fatalf("expected %s to be stored", nm)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,310 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+// the number of buckets written per transaction when importing without a batch size.
+const importBatch = 64
+
+// how `ImportBuckets` handles top-level buckets that already exist.
+type Merge int
+
+const (
+	// replace the existing bucket, along with every bucket nested in it.
+	MergeOverwrite Merge = iota
+
+	// keep the existing bucket, skipping the imported one.
+	MergeSkip
+)
+
+// create a database from an export stream, see `ExportTo`.
+// the header is validated before anything is written, then the buckets are written in batches as they're read,
+// so the export is never held in memory. the buckets are stored the way a flush stores them, including sharding, the
+// key index and which buckets are encrypted. a count is written with the first batch and corrected by the last, so an
+// interrupted import is detected by `Open`. if the stream is corrupt the imported buckets are deleted.
+func ImportFrom(name string, r io.Reader, opts ...Option) error {
+	cfg := newConfig(opts...)
+
+	// validate the header.
+	er, err := newExportReader(r, cfg)
+	if err != nil {
+		return err
+	}
+
+	b, exist, err := cfg.backend(name)
+	if err != nil {
+		return err
+	}
+
+	defer b.Close()
+
+	// ensure we don't overwrite an existing database.
+	if exist {
+		return walletdb.ErrDbExists
+	}
+
+	// set up encryption.
+	meta, err := cfg.encryption(nil)
+	if err != nil {
+		return err
+	}
+
+	size := cfg.batchSize
+	if size <= 0 {
+		size = importBatch
+	}
+
+	// the records written so far.
+	var written []tempdb.BucketID
+
+	// sharded buckets are split into their record and up to 256 shards, so the number of records is only known once
+	// every bucket is read. until the last batch the count is the most records the buckets can be split into, so an
+	// interrupted import never matches it.
+	count := er.left
+
+	if cfg.shardKeys > 0 {
+		count *= 257
+	}
+
+	ch := &Changes{
+		Records: Records{
+			Buckets: make(map[tempdb.BucketID][]byte),
+			Meta: map[string][]byte{
+				countKey:  []byte(strconv.FormatUint(count, 10)),
+				schemaKey: []byte(strconv.Itoa(schemaVersion)),
+			},
+		},
+	}
+
+	// store the encryption metadata with the first batch.
+	for k, v := range meta {
+		ch.Meta[k] = v
+	}
+
+	// the top-level bucket and path of every bucket, buckets are exported after the bucket they're nested in.
+	rts := make(map[tempdb.BucketID]string)
+	pths := make(map[tempdb.BucketID]string)
+
+	// the records stored in plaintext.
+	plain := make(map[tempdb.BucketID]bool)
+
+	for {
+		bkt, err := er.next()
+		if err == io.EOF {
+			break
+		}
+
+		if err == nil && bkt.Parent != tempdb.RootBucketID {
+			if _, ok := rts[bkt.Parent]; !ok {
+				err = fmt.Errorf("%w: bucket %d is before its parent", ErrNotExport, bkt.ID)
+			}
+		}
+
+		if err != nil {
+			// remove the imported buckets and reset the count, so the database can still be opened.
+			werr := b.Write(&Changes{
+				Records: Records{
+					Meta: map[string][]byte{
+						countKey: []byte("0"),
+					},
+				},
+				Deletes: written,
+			})
+
+			return errors.Join(err, werr)
+		}
+
+		if bkt.Parent == tempdb.RootBucketID {
+			rts[bkt.ID] = string(bkt.Key)
+			pths[bkt.ID] = printable(bkt.Key)
+		} else {
+			rts[bkt.ID] = rts[bkt.Parent]
+			pths[bkt.ID] = pths[bkt.Parent] + "/" + printable(bkt.Key)
+		}
+
+		rt := rts[bkt.ID]
+
+		// write the bucket the way a flush does.
+		for _, rec := range cfg.split(bkt) {
+			v, err := cfg.marshal(&rec)
+			if err != nil {
+				return err
+			}
+
+			ch.Buckets[rec.ID], plain[rec.ID], err = cfg.encryptIn(v, rt, recordData(bucketStore, rec.ID))
+			if err != nil {
+				return err
+			}
+
+			if cfg.blobs[rt] {
+				if ch.Blobs == nil {
+					ch.Blobs = make(map[tempdb.BucketID]bool)
+				}
+
+				ch.Blobs[rec.ID] = true
+			}
+
+			if cfg.index {
+				if ch.Names == nil {
+					ch.Names = make(map[tempdb.BucketID]string)
+				}
+
+				ch.Names[rec.ID], err = cfg.indexPath(pths[bkt.ID], rt)
+				if err != nil {
+					return err
+				}
+			}
+
+			written = append(written, rec.ID)
+		}
+
+		if cfg.keyIndex {
+			if ch.Keys == nil {
+				ch.Keys = make(map[tempdb.BucketID][]byte)
+			}
+
+			ch.Keys[bkt.ID], err = cfg.encodeKeys(&bkt, rt)
+			if err != nil {
+				return err
+			}
+		}
+
+		// write the batch once it's full.
+		if len(ch.Buckets) < size {
+			continue
+		}
+
+		// store the plaintext records with every batch, so they match the records written if a later batch fails.
+		if cfg.aead != nil {
+			ch.Meta[plaintextKey] = formatKeys(plain)
+		}
+
+		err = b.Write(ch)
+		if err != nil {
+			return err
+		}
+
+		ch = &Changes{
+			Records: Records{
+				Buckets: make(map[tempdb.BucketID][]byte),
+				Meta:    make(map[string][]byte),
+			},
+		}
+	}
+
+	// write the last batch, with the number of records written.
+	ch.Meta[countKey] = []byte(strconv.Itoa(len(written)))
+
+	if cfg.aead != nil {
+		ch.Meta[plaintextKey] = formatKeys(plain)
+	}
+
+	return b.Write(ch)
+}
+
+// merge the top-level buckets in an export into the database, in a single transaction.
+// this is meant for exports of some buckets from `ExportBuckets`, the buckets are given new IDs
+// so they don't collide with the database's buckets. merge decides what happens to existing buckets.
+func (db *DB) ImportBuckets(data []byte, merge Merge) error {
+	children, err := readChildren(data, db.cfg)
+	if err != nil {
+		return err
+	}
+
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return importInto(tx.(*Transaction), children, merge)
+	})
+}
+
+// read every bucket in an export, grouped by parent, decrypting them with the config if they're encrypted.
+func readChildren(data []byte, cfg *config) (map[tempdb.BucketID][]tempdb.Bucket, error) {
+	er, err := newExportReader(bytes.NewReader(data), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[tempdb.BucketID][]tempdb.Bucket)
+
+	for {
+		bkt, err := er.next()
+		if err == io.EOF {
+			return children, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		children[bkt.Parent] = append(children[bkt.Parent], bkt)
+	}
+}
+
+// import the top-level buckets read by `readChildren` into the transaction.
+func importInto(ttx *Transaction, children map[tempdb.BucketID][]tempdb.Bucket, merge Merge) error {
+	for _, bkt := range children[tempdb.RootBucketID] {
+		if existing := ttx.ReadWriteBucket(bkt.Key); existing != nil {
+			if merge == MergeSkip {
+				continue
+			}
+
+			ttx.remove(bkt.Key)
+		}
+
+		nbkt, err := ttx.CreateTopLevelBucket(bkt.Key)
+		if err != nil {
+			return err
+		}
+
+		err = restore(nbkt, &bkt, children)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove a top-level bucket and every bucket nested in it.
+func (ttx *Transaction) remove(name []byte) {
+	rts := roots(ttx.State.Buckets)
+
+	ttx.State.Buckets = slices.DeleteFunc(ttx.State.Buckets, func(b tempdb.Bucket) bool {
+		return rts[b.ID] == string(name)
+	})
+}
+
+// restore a bucket's values and nested buckets from an export.
+func restore(dst walletdb.ReadWriteBucket, src *tempdb.Bucket, children map[tempdb.BucketID][]tempdb.Bucket) error {
+	for k, v := range src.Value {
+		err := dst.Put([]byte(k), v)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, child := range children[src.ID] {
+		// create the nested bucket, it replaces the empty value stored under its key.
+		nbkt, err := dst.CreateBucket(child.Key)
+		if err != nil {
+			return err
+		}
+
+		err = restore(nbkt, &child, children)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
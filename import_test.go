@@ -0,0 +1,294 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestImportFrom(t *testing.T) {
+	db := populated(t, "import-source.db")
+
+	buf := new(bytes.Buffer)
+
+	err := db.(*DB).ExportTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Clone(buf.Bytes())
+
+	// import in batches of 1, so the import is written incrementally.
+	err = ImportFrom("import.db", buf, WithBatchSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idb, err := walletdb.Open("localdb", "import.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure every bucket was imported.
+	src := db.(*DB).State.Buckets
+	dst := idb.(*DB).State.Buckets
+
+	if len(dst) != len(src) {
+		t.Fatalf("expected %d buckets: got %d", len(src), len(dst))
+	}
+
+	for i := range src {
+		if !equal(&dst[i], &src[i]) {
+			t.Fatalf("expected bucket %v: got %v", src[i], dst[i])
+		}
+	}
+
+	// ensure an existing database is not overwritten.
+	err = ImportFrom("import.db", bytes.NewReader(data))
+	if !errors.Is(err, walletdb.ErrDbExists) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbExists, err)
+	}
+
+	// ensure data that isn't an export is rejected before creating the database.
+	err = ImportFrom("import-invalid.db", bytes.NewReader([]byte("not an export")))
+	if !errors.Is(err, ErrNotExport) {
+		t.Fatalf("expected %v: got %v", ErrNotExport, err)
+	}
+
+	_, err = walletdb.Open("localdb", "import-invalid.db")
+	if !errors.Is(err, walletdb.ErrDbDoesNotExist) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbDoesNotExist, err)
+	}
+
+	// ensure a corrupt export is rolled back.
+	data[len(data)-crc32.Size-1] ^= 0xff
+
+	err = ImportFrom("import-corrupt.db", bytes.NewReader(data), WithBatchSize(1))
+	if err == nil {
+		t.Fatal("expected a corrupt export to fail")
+	}
+
+	cdb, err := walletdb.Open("localdb", "import-corrupt.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(cdb.(*DB).State.Buckets); n != 0 {
+		t.Fatalf("expected 0 buckets: got %d", n)
+	}
+}
+
+func TestImportBuckets(t *testing.T) {
+	src := populated(t, "import-buckets-source.db")
+
+	// add a bucket which isn't exported.
+	err := walletdb.Update(src, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("private"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := src.(*DB).ExportBuckets([]byte("parent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the export only has the parent and its child.
+	bkts, err := readAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bkts) != 2 {
+		t.Fatalf("expected 2 buckets: got %d", len(bkts))
+	}
+
+	// create a database with an existing parent, and another bucket to shift the IDs.
+	dst, err := walletdb.Create("localdb", "import-buckets.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(dst, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("other"))
+		if err != nil {
+			return err
+		}
+
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("a"), []byte("existing"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check the value of a, and that b is in the child.
+	check := func(db walletdb.DB, a string) {
+		err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+			if tx.ReadBucket([]byte("private")) != nil {
+				t.Fatal("expected the private bucket not to be imported")
+			}
+
+			if tx.ReadBucket([]byte("other")) == nil {
+				t.Fatal("expected the other bucket to be kept")
+			}
+
+			bkt := tx.ReadBucket([]byte("parent"))
+
+			if v := bkt.Get([]byte("a")); string(v) != a {
+				t.Fatalf("expected %s: got %s", a, v)
+			}
+
+			nbkt := bkt.NestedReadBucket([]byte("child"))
+
+			if a == "existing" {
+				if nbkt != nil {
+					t.Fatal("expected the child not to be imported")
+				}
+
+				return nil
+			}
+
+			if v := nbkt.Get([]byte("b")); string(v) != "2" {
+				t.Fatalf("expected 2: got %s", v)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure skipping keeps the existing bucket.
+	err = dst.(*DB).ImportBuckets(data, MergeSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(dst, "existing")
+
+	// ensure overwriting replaces the existing bucket, and is stored.
+	err = dst.(*DB).ImportBuckets(data, MergeOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(dst, "1")
+
+	dst, err = walletdb.Open("localdb", "import-buckets.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(dst, "1")
+}
+
+func TestImportFromOptions(t *testing.T) {
+	src, err := walletdb.Create("localdb", "import-options-source.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// put 26 keys starting with different bytes in a secret bucket, and a value in a public one.
+	err = walletdb.Update(src, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("secret"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 26; i++ {
+			err = bkt.Put([]byte{'a' + byte(i)}, []byte("hidden"))
+			if err != nil {
+				return err
+			}
+		}
+
+		bkt, err = tx.CreateTopLevelBucket([]byte("public"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("shown"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+
+	err = src.(*DB).ExportTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nm := "import-options.db"
+
+	key := bytes.Repeat([]byte{1}, 32)
+	encrypted := WithEncryptedBuckets(func(name []byte) bool { return string(name) == "secret" })
+
+	// import in batches of 2, so the count is corrected by the last batch.
+	err = ImportFrom(nm, buf, WithEncryptionKey(key), encrypted, WithSharding(10), WithKeyIndex(), WithBatchSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := walletdb.Open("localdb", nm, WithEncryptionKey(key), encrypted, WithSharding(10), WithKeyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	// ensure the secret bucket is sharded into its record and 26 shards, alongside the public bucket's record.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(recs.Buckets); n != 28 {
+		t.Fatalf("expected 28 records: got %d", n)
+	}
+
+	// ensure only the public bucket is stored in plaintext.
+	var shown int
+
+	for _, v := range recs.Buckets {
+		if bytes.Contains(v, []byte("hidden")) {
+			t.Fatal("expected the secret bucket to be encrypted")
+		}
+
+		if bytes.Contains(v, []byte("shown")) {
+			shown++
+		}
+	}
+
+	if shown != 1 {
+		t.Fatalf("expected the public bucket in plaintext: got %d records", shown)
+	}
+
+	// ensure the keys were indexed.
+	keys, err := db.(*DB).StoredKeys([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 26 {
+		t.Fatalf("expected 26 indexed keys: got %d", len(keys))
+	}
+
+	if v := getValue(t, db, "public"); v != "shown" {
+		t.Fatalf("expected shown: got %q", v)
+	}
+}
@@ -0,0 +1,105 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"strconv"
+
+	"github.com/linden/tempdb"
+)
+
+// the metadata key for the journal of an unfinished flush.
+const journalKey = "journal"
+
+// journal flushes which take more than 1 write to the backend, such as with `WithBatchSize`, so a flush that's
+// interrupted part way, by the page closing or crashing, is replayed on open instead of leaving the database
+// inconsistent. every record in the flush is written to the journal first, so journaled flushes write twice as much.
+// flushes which take a single write are already atomic, so they aren't journaled.
+func WithJournal() Option {
+	return func(cfg *config) {
+		cfg.journal = true
+	}
+}
+
+// a flush, as it will be stored.
+type journal struct {
+	// the encoded buckets to put, by key.
+	Buckets map[tempdb.BucketID][]byte
+
+	// the keys of the records to delete.
+	Deletes []tempdb.BucketID
+
+	// the number of records once the flush is written.
+	Count int
+
+	// the keys of the plaintext records once the flush is written, see `formatKeys`.
+	Plaintext []byte
+}
+
+// write the journal before the flush.
+func writeJournal(b Backend, j *journal) error {
+	buf := new(bytes.Buffer)
+
+	err := gob.NewEncoder(buf).Encode(j)
+	if err != nil {
+		return err
+	}
+
+	return b.Write(&Changes{
+		Records: Records{
+			Meta: map[string][]byte{
+				journalKey: buf.Bytes(),
+			},
+		},
+	})
+}
+
+// replay the journal of an unfinished flush in a single write, updating the loaded records to match.
+// the journal is empty once its flush has finished.
+func replay(b Backend, recs *Records) error {
+	v := recs.Meta[journalKey]
+	if len(v) == 0 {
+		return nil
+	}
+
+	var j journal
+
+	err := gobDecode(v, &j)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+
+	meta := map[string][]byte{
+		countKey:     []byte(strconv.Itoa(j.Count)),
+		plaintextKey: j.Plaintext,
+		journalKey:   {},
+	}
+
+	err = b.Write(&Changes{
+		Records: Records{
+			Buckets: j.Buckets,
+			Meta:    meta,
+		},
+		Deletes: j.Deletes,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range j.Deletes {
+		delete(recs.Buckets, id)
+	}
+
+	for id, v := range j.Buckets {
+		recs.Buckets[id] = v
+	}
+
+	for k, v := range meta {
+		recs.Meta[k] = v
+	}
+
+	return nil
+}
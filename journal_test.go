@@ -0,0 +1,225 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// a backend which fails every write after a number of writes, like the page closing mid-flush.
+type crashingBackend struct {
+	Backend
+	writes int
+	after  int
+}
+
+func (b *crashingBackend) Write(ch *Changes) error {
+	b.writes++
+
+	if b.writes > b.after {
+		return errors.New("crashed")
+	}
+
+	return b.Backend.Write(ch)
+}
+
+func TestJournal(t *testing.T) {
+	// the name of the database.
+	nm := "journal.db"
+
+	var cb *crashingBackend
+
+	crashing := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		cb = &crashingBackend{Backend: b, after: -1}
+		return cb, exist, nil
+	}
+
+	db, err := walletdb.Create("localdb", nm, WithBackend(crashing), WithBatchSize(1), WithJournal())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// defer the buckets, so they are written by a single flush.
+	db.(*DB).Defer([]byte("a"), []byte("b"), []byte("c"))
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, nm := range []string{"a", "b", "c"} {
+			bkt, err := tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put([]byte("key"), []byte(nm))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// crash after the journal and the first batch are written.
+	cb.after = cb.writes + 2
+
+	err = db.(*DB).Flush()
+	if err == nil {
+		t.Fatal("expected the flush to crash")
+	}
+
+	db.Close()
+
+	// ensure the journal is replayed on open.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for _, nm := range []string{"a", "b", "c"} {
+			bkt := tx.ReadBucket([]byte(nm))
+			if bkt == nil {
+				return fmt.Errorf("expected %s to be recovered", nm)
+			}
+
+			if v := bkt.Get([]byte("key")); string(v) != nm {
+				return fmt.Errorf("expected %s: got %s", nm, v)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the journal was cleared.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recs.Meta[journalKey]) != 0 {
+		t.Fatal("expected the journal to be cleared")
+	}
+}
+
+func TestJournalCleared(t *testing.T) {
+	// the name of the database.
+	nm := "journal-cleared.db"
+
+	var cb *crashingBackend
+
+	crashing := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		cb = &crashingBackend{Backend: b, after: math.MaxInt}
+		return cb, exist, nil
+	}
+
+	db, err := walletdb.Create("localdb", nm, WithBackend(crashing), WithBatchSize(1), WithJournal())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// defer the buckets, so they are written by a single flush.
+	db.(*DB).Defer([]byte("a"), []byte("b"))
+
+	put := func(v string, names ...string) {
+		t.Helper()
+
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			for _, nm := range names {
+				var err error
+
+				bkt := tx.ReadWriteBucket([]byte(nm))
+				if bkt == nil {
+					bkt, err = tx.CreateTopLevelBucket([]byte(nm))
+					if err != nil {
+						return err
+					}
+				}
+
+				err = bkt.Put([]byte("key"), []byte(v))
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put("old", "a", "b")
+
+	err = db.(*DB).Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put("mid", "a", "b")
+
+	// crash after the journal and the first batch are written.
+	cb.after = cb.writes + 2
+
+	err = db.(*DB).Flush()
+	if err == nil {
+		t.Fatal("expected the flush to crash")
+	}
+
+	cb.after = math.MaxInt
+
+	// flush in single writes, which aren't journaled but must clear the journal of the failed flush.
+	err = db.(*DB).Flush([]byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put("new", "a")
+
+	err = db.(*DB).Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure the stale journal isn't replayed over the newer records.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for nm, exp := range map[string]string{"a": "new", "b": "mid"} {
+			if v := tx.ReadBucket([]byte(nm)).Get([]byte("key")); string(v) != exp {
+				return fmt.Errorf("expected %s to be %s: got %s", nm, exp, v)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"syscall/js"
+	"unsafe"
+
+	"github.com/linden/indexeddb"
+)
+
+// get the javascript value underlying an indexeddb type.
+// the indexeddb package doesn't expose them, but each type only holds its value. remove it once indexeddb has an
+// accessor, `TestValueLayout` checks the types still only hold their value.
+func value[T indexeddb.DB | indexeddb.Transaction | indexeddb.Store | indexeddb.Upgrade](v *T) js.Value {
+	return *(*js.Value)(unsafe.Pointer(v))
+}
+
+// wait for a promise to settle.
+func await(p js.Value) (js.Value, error) {
+	done := make(chan js.Value, 1)
+	fail := make(chan error, 1)
+
+	// handle the promise resolving.
+	then := js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- args[0]
+		return nil
+	})
+
+	defer then.Release()
+
+	// handle the promise rejecting.
+	catch := js.FuncOf(func(this js.Value, args []js.Value) any {
+		fail <- jsError(args[0])
+		return nil
+	})
+
+	defer catch.Release()
+
+	p.Call("then", then, catch)
+
+	select {
+	case v := <-done:
+		return v, nil
+
+	case err := <-fail:
+		return js.Value{}, err
+	}
+}
+
+// wait for an `IDBRequest` to succeed or fail.
+func request(req js.Value) (js.Value, error) {
+	done := make(chan struct{}, 1)
+	fail := make(chan error, 1)
+
+	// handle the success event.
+	success := js.FuncOf(func(this js.Value, args []js.Value) any {
+		done <- struct{}{}
+		return nil
+	})
+
+	defer success.Release()
+
+	// handle the error event.
+	failure := js.FuncOf(func(this js.Value, args []js.Value) any {
+		fail <- jsError(req.Get("error"))
+		return nil
+	})
+
+	defer failure.Release()
+
+	req.Set("onsuccess", success)
+	req.Set("onerror", failure)
+
+	select {
+	case <-done:
+		return req.Get("result"), nil
+
+	case err := <-fail:
+		return js.Value{}, err
+	}
+}
+
+// convert a javascript error to a Go error.
+func jsError(v js.Value) error {
+	// keep errors as a `js.Error`, so they can be matched by name.
+	if v.Type() == js.TypeObject {
+		return js.Error{Value: v}
+	}
+
+	// ensure we have a method to convert to a string.
+	if v.IsUndefined() || v.IsNull() {
+		return errors.New("invalid javascript error")
+	}
+
+	return errors.New(v.Call("toString").String())
+}
+
+// check if an error is a javascript error with the name, such as `NotFoundError`.
+func isJSError(err error, name string) bool {
+	var jerr js.Error
+	if !errors.As(err, &jerr) {
+		return false
+	}
+
+	return jerr.Get("name").String() == name
+}
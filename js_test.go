@@ -0,0 +1,25 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"reflect"
+	"syscall/js"
+	"testing"
+
+	"github.com/linden/indexeddb"
+)
+
+func TestValueLayout(t *testing.T) {
+	// ensure every type read by `value` only holds its javascript value.
+	for _, typ := range []reflect.Type{
+		reflect.TypeOf(indexeddb.DB{}),
+		reflect.TypeOf(indexeddb.Transaction{}),
+		reflect.TypeOf(indexeddb.Store{}),
+		reflect.TypeOf(indexeddb.Upgrade{}),
+	} {
+		if typ.NumField() != 1 || typ.Field(0).Type != reflect.TypeOf(js.Value{}) {
+			t.Fatalf("expected %s to only hold a js.Value", typ)
+		}
+	}
+}
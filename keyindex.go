@@ -0,0 +1,153 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"slices"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+// the key index is disabled or the bucket hasn't been indexed yet.
+var ErrNotIndexed = errors.New("bucket is not in the key index")
+
+// store the keys of every bucket separately from its record, so they can be listed without decoding the values, see
+// `StoredKeys`. only the indexeddb backend stores it, in the "key_index" object store, keyed by bucket ID like the
+// buckets. every index is the bucket's sorted keys encoded with gob, including the keys of nested buckets, and it's
+// encrypted like the bucket. a bucket is indexed when it's written, so buckets stored before the option was enabled
+// aren't indexed until they change.
+func WithKeyIndex() Option {
+	return func(cfg *config) {
+		cfg.keyIndex = true
+	}
+}
+
+// get the keys of a top-level bucket from the key index, reading them from storage without loading its values.
+func (db *DB) StoredKeys(name []byte) ([][]byte, error) {
+	b := indexedDB(db.backend)
+	if b == nil {
+		return nil, ErrNotIndexedDB
+	}
+
+	db.lock.Lock()
+
+	id, ok := topLevel(db.State.Buckets, name)
+	plain := db.plain[id]
+
+	db.lock.Unlock()
+
+	if !ok {
+		return nil, walletdb.ErrBucketNotFound
+	}
+
+	v, err := b.keys(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !plain {
+		v, err = db.cfg.decryptRecord(v, keyStore, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keys [][]byte
+
+	err = gobDecode(v, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// encode the keys of the buckets the records belong to, encrypting them like the records.
+func (db *DB) indexKeys(recs []tempdb.Bucket, rts map[tempdb.BucketID]string) (map[tempdb.BucketID][]byte, error) {
+	// index the buckets.
+	byID := make(map[tempdb.BucketID]*tempdb.Bucket)
+
+	for i := range db.State.Buckets {
+		byID[db.State.Buckets[i].ID] = &db.State.Buckets[i]
+	}
+
+	idx := make(map[tempdb.BucketID][]byte)
+
+	for _, rec := range recs {
+		// shards are indexed with their bucket.
+		id := owner(rec.ID)
+
+		bkt, ok := byID[id]
+		if _, done := idx[id]; done || !ok {
+			continue
+		}
+
+		var err error
+
+		idx[id], err = db.cfg.encodeKeys(bkt, rts[id])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return idx, nil
+}
+
+// encode the sorted keys of a bucket in the top-level bucket for the key index, encrypting them like its record.
+func (cfg *config) encodeKeys(bkt *tempdb.Bucket, root string) ([]byte, error) {
+	keys := make([][]byte, 0, len(bkt.Value))
+
+	for k := range bkt.Value {
+		keys = append(keys, []byte(k))
+	}
+
+	slices.SortFunc(keys, bytes.Compare)
+
+	buf := new(bytes.Buffer)
+
+	err := gob.NewEncoder(buf).Encode(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	v, _, err := cfg.encryptIn(buf.Bytes(), root, recordData(keyStore, bkt.ID))
+	return v, err
+}
+
+// get the ID of a top-level bucket.
+func topLevel(bkts []tempdb.Bucket, name []byte) (tempdb.BucketID, bool) {
+	for _, bkt := range bkts {
+		if bkt.Parent == tempdb.RootBucketID && bytes.Equal(bkt.Key, name) {
+			return bkt.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// get a bucket's encoded keys from the key index.
+func (b *idbBackend) keys(id tempdb.BucketID) ([]byte, error) {
+	itx, err := b.idb.NewTransaction([]string{b.store(keyStore)}, indexeddb.ReadMode)
+	if err != nil {
+		return nil, err
+	}
+
+	b.trips.transaction()
+	b.trips.get(1)
+
+	v, err := itx.Store(b.store(keyStore)).Get(uint64(id))
+	if errors.Is(err, indexeddb.ErrValueNotFound) {
+		return nil, ErrNotIndexed
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return unquote(*v)
+}
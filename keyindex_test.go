@@ -0,0 +1,66 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+func TestKeyIndex(t *testing.T) {
+	db, err := walletdb.Create("localdb", "keyindex.db", WithKeyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"c", "a", "b"} {
+			err = bkt.Put([]byte(k), []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the bucket's record, so listing the keys fails if it's decoded.
+	id, _ := topLevel(db.(*DB).State.Buckets, []byte("bucket"))
+
+	err = db.(*DB).backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{id: []byte("corrupt")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := db.(*DB).StoredKeys([]byte("bucket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 3 || string(keys[0]) != "a" || string(keys[1]) != "b" || string(keys[2]) != "c" {
+		t.Fatalf("expected [a b c]: got %q", keys)
+	}
+
+	// ensure a missing bucket is reported.
+	_, err = db.(*DB).StoredKeys([]byte("missing"))
+	if !errors.Is(err, walletdb.ErrBucketNotFound) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrBucketNotFound, err)
+	}
+}
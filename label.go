@@ -0,0 +1,36 @@
+//go:build js && wasm
+
+package localdb
+
+// the metadata key for the database's label.
+const labelKey = "label"
+
+// get the database's label, empty if it doesn't have one, see `SetLabel`.
+func (db *DB) Label() string {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.label
+}
+
+// set a human-readable label for the database, such as to list wallets by name instead of by database name.
+// it's stored in the metadata, so it's kept when the database is reopened.
+func (db *DB) SetLabel(label string) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	err := db.backend.Write(&Changes{
+		Records: Records{
+			Meta: map[string][]byte{
+				labelKey: []byte(label),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	db.label = label
+
+	return nil
+}
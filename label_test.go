@@ -0,0 +1,44 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestLabel(t *testing.T) {
+	nm := "label.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := db.(*DB).Label(); l != "" {
+		t.Fatalf("expected no label: got %q", l)
+	}
+
+	err = db.(*DB).SetLabel("Savings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the label is kept.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if l := db.(*DB).Label(); l != "Savings" {
+		t.Fatalf("expected Savings: got %q", l)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"slices"
+	"time"
+)
+
+// the number of recent flush durations kept.
+const latencySamples = 256
+
+// the distribution of recent flush durations, see `FlushLatency`.
+type Latency struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+
+	// the number of flushes the percentiles are from.
+	Samples int
+}
+
+// a ring buffer of recent flush durations.
+type latencies struct {
+	samples [latencySamples]time.Duration
+
+	// the number of durations ever added.
+	n int
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.samples[l.n%latencySamples] = d
+	l.n++
+}
+
+func (l *latencies) stats() Latency {
+	sorted := slices.Clone(l.samples[:min(l.n, latencySamples)])
+	slices.Sort(sorted)
+
+	// get the nearest-rank percentile.
+	pct := func(p int) time.Duration {
+		if len(sorted) == 0 {
+			return 0
+		}
+
+		return sorted[(p*len(sorted)+99)/100-1]
+	}
+
+	return Latency{
+		P50:     pct(50),
+		P95:     pct(95),
+		P99:     pct(99),
+		Samples: len(sorted),
+	}
+}
+
+// get the distribution of how long recent flushes took to write, including the flushes on commit.
+// only the last 256 flushes are kept.
+func (db *DB) FlushLatency() Latency {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.latency.stats()
+}
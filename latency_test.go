@@ -0,0 +1,43 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestFlushLatency(t *testing.T) {
+	db, err := walletdb.Create("localdb", "latency.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lat := db.(*DB).FlushLatency(); lat.Samples != 0 {
+		t.Fatalf("expected no samples: got %d", lat.Samples)
+	}
+
+	// the number of flushes.
+	n := 10
+
+	for i := 0; i < n; i++ {
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			_, err := tx.CreateTopLevelBucket([]byte(fmt.Sprint(i)))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lat := db.(*DB).FlushLatency()
+	if lat.Samples != n {
+		t.Fatalf("expected %d samples: got %d", n, lat.Samples)
+	}
+
+	if lat.P99 <= 0 || lat.P50 > lat.P95 || lat.P95 > lat.P99 {
+		t.Fatalf("expected increasing percentiles: got %+v", lat)
+	}
+}
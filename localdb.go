@@ -4,80 +4,156 @@ package localdb
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
-	"strings"
+	"sync"
 	"syscall/js"
+	"time"
+	"unsafe"
 
 	"github.com/btcsuite/btcwallet/walletdb"
-	"github.com/linden/indexeddb"
 	"github.com/linden/tempdb"
 )
 
-const (
-	// the name of the object store for the buckets.
-	bucketStore = "buckets"
+// the metadata key for the number of stored buckets.
+const countKey = "count"
 
-	// the version of the indexeddb database.
-	version = 1
+var (
+	// the stored bucket count does not match the number of buckets loaded.
+	ErrCountMismatch = errors.New("stored bucket count does not match the buckets loaded")
+
+	// a stored record can't be decoded.
+	ErrMalformedRecord = errors.New("malformed record")
+
+	// the transaction was committed in memory, but writing it to the backend failed, such as when the quota is
+	// exceeded. the changes are kept in memory and pending, like a deferred bucket, until `Flush` writes them.
+	// they are lost if the page is closed before then.
+	ErrNotFlushed = errors.New("transaction was committed but not flushed")
 )
 
-// share a logger with tempdb.
-var Logger = tempdb.Logger
+// get the logger shared with tempdb, its default discards every message until `tempdb.Logger` is set.
+func Logger() *slog.Logger {
+	if tempdb.Logger == nil {
+		tempdb.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	return tempdb.Logger
+}
 
 type DB struct {
-	idb *indexeddb.DB
+	backend Backend
 	*tempdb.DB
+
+	cfg *config
+
+	// guards the flush state below.
+	lock sync.Mutex
+
+	// the top-level bucket key of every stored record, by key.
+	records map[tempdb.BucketID]string
+
+	// the top-level buckets that are not flushed on commit.
+	deferred map[string]bool
+
+	// the deferred top-level buckets with changes that have not been flushed.
+	dirty map[string]bool
+
+	// the keys of the records stored in plaintext, see `WithEncryptedBuckets`.
+	plain map[tempdb.BucketID]bool
+
+	// the hash of every record's encoding when it was written, by key.
+	// records loaded by `Open` have no hash until they're written.
+	hashes map[tempdb.BucketID][sha256.Size]byte
+
+	// flushes the database once it's idle, see `WithIdleFlush`.
+	idleTimer *time.Timer
+
+	// the number of buckets and keys, see `Counts`.
+	buckets int
+	keys    int
+
+	// the number of commits, to write a snapshot every nth commit.
+	commits int
+
+	// when every top-level bucket was last modified, see `WithSyncer` and `WithBucketTTL`.
+	modified map[string]time.Time
+
+	// the time of the remote change every top-level bucket was last merged with.
+	merged map[string]time.Time
+
+	// when every deleted top-level bucket was deleted, see `Tombstones`.
+	tombstones map[string]time.Time
+
+	// the remote changes being applied by `Pull`, by top-level bucket.
+	pulled map[string]SyncChange
+
+	// the conflicts the resolver couldn't resolve in the last pull.
+	conflicts []Conflict
+
+	// the top-level buckets which haven't been pushed, such as while the browser is offline, and the function
+	// pushing them once it's back online.
+	queued   map[string]bool
+	onOnline js.Func
+
+	// the number of open handles, see `WithSharedHandle`.
+	refs int
+
+	// the database's label, see `SetLabel`.
+	label string
+
+	// the indexeddb requests made so far and by the last of each operation, see `WithRoundTrips`.
+	trips *RoundTrips
+	stats OperationStats
+
+	// the channels flush events are sent to, see `Subscribe`.
+	subscribers []chan FlushEvent
+
+	// called when the committed state changes, see `OnStateChange`.
+	listeners []func()
+
+	// called when a background flush fails, see `SetFlushErrorHandler`.
+	onFlushError func(error)
+
+	// the changes being recorded, see `RecordChanges`.
+	changeLog *ChangeLog
+
+	// how long recent flushes took.
+	latency latencies
+
+	// how long each phase of opening took, see `OpenTiming`.
+	openTiming OpenTiming
 }
 
 func (db *DB) BeginReadWriteTx() (walletdb.ReadWriteTx, error) {
 	// create the transaction.
-	tx, err := db.DB.BeginReadWriteTx()
+	rwtx, err := db.DB.BeginReadWriteTx()
 	if err != nil {
 		return nil, err
 	}
 
-	// cast to a TempDB transaction so we can access the state.
-	ttx := tx.(*tempdb.Transaction)
-
-	// add a commit hook to update the state.
-	// TODO: handle errors.
-	tx.OnCommit(func() {
-		// create a new read/write transaction.
-		itx, err := db.idb.NewTransaction([]string{bucketStore}, indexeddb.ReadWriteMode)
-		if err != nil {
-			panic(err)
-		}
-
-		// open the bucket store.
-		btch := itx.Store(bucketStore).Batch()
-
-		// save every bucket by index.
-		for i, bkt := range ttx.State.Buckets {
-			// create a buffer.
-			buf := new(bytes.Buffer)
+	// keep the buckets from before the transaction, so we can find what changed.
+	// the transaction holds the lock, so the state can't change underneath us.
+	prev := db.State.Buckets
 
-			// encode the bucket into the buffer.
-			err = gob.NewEncoder(buf).Encode(&bkt)
-			if err != nil {
-				panic(err)
-			}
-
-			// quote the string, since Go strings aren't UTF-8.
-			// https://go.dev/blog/strings.
-			v := strconv.Quote(buf.String())
+	// wrap the transaction, so it supports savepoints and validators.
+	tx := &Transaction{
+		Transaction: rwtx.(*tempdb.Transaction),
 
-			err = btch.Put(i, v)
-			if err != nil {
-				panic(err)
-			}
-		}
+		cfg:  db.cfg,
+		prev: prev,
+	}
 
-		err = btch.Wait()
-		if err != nil {
-			panic(err)
-		}
+	// add a commit hook to flush the changes, the error is returned by `Commit`.
+	tx.OnCommit(func() {
+		db.stateChanged()
+		tx.err = db.commit(prev, tx.opts)
 	})
 
 	return tx, nil
@@ -87,12 +163,36 @@ func (db *DB) BeginReadWriteTx() (walletdb.ReadWriteTx, error) {
 func (db *DB) Update(fn func(tx walletdb.ReadWriteTx) error, reset func()) error {
 	reset()
 
+	return db.UpdateWith(UpdateOptions{}, fn)
+}
+
+// we need to override `tempdb.Batch` too, since it calls `tempdb.Update` which would skip our update hook.
+// transactions aren't combined, every call is committed and flushed like `Update`.
+func (db *DB) Batch(fn func(tx walletdb.ReadWriteTx) error) error {
+	return db.UpdateWith(UpdateOptions{}, fn)
+}
+
+// how a transaction is flushed, see `UpdateWith`.
+type UpdateOptions struct {
+	// flush in the background once the commit returns, instead of before, for changes which can be lost such as UI
+	// state. a failed flush leaves the changes pending, see `Pending`.
+	Async bool
+
+	// flush on commit even if the changed buckets are deferred or the database flushes once it's idle, for critical
+	// changes such as a balance, see `Defer` and `WithIdleFlush`.
+	Immediate bool
+}
+
+// update the database like `walletdb.Update`, with the options controlling how the transaction is flushed.
+func (db *DB) UpdateWith(opts UpdateOptions, fn func(tx walletdb.ReadWriteTx) error) error {
 	// create a new transaction.
 	tx, err := db.BeginReadWriteTx()
 	if err != nil {
 		return err
 	}
 
+	tx.(*Transaction).opts = opts
+
 	// call the function.
 	err = fn(tx)
 	if err != nil {
@@ -100,8 +200,8 @@ func (db *DB) Update(fn func(tx walletdb.ReadWriteTx) error, reset func()) error
 		return err
 	}
 
-	// cast to a TempDB transaction so we can access the rollback status.
-	ttx := tx.(*tempdb.Transaction)
+	// cast to our transaction so we can access the rollback status.
+	ttx := tx.(*Transaction)
 
 	// ensure the transaciton has not been rolledback.
 	if ttx.Rolledback {
@@ -111,6 +211,341 @@ func (db *DB) Update(fn func(tx walletdb.ReadWriteTx) error, reset func()) error
 	return tx.Commit()
 }
 
+// write the buckets and delete the records, in batches if configured.
+func (db *DB) write(puts []tempdb.Bucket, dels []tempdb.BucketID) (err error) {
+	// split sharded buckets into their records.
+	puts, dels = db.shard(puts, dels)
+
+	// encode every bucket, so we can skip stored buckets whose encoding hasn't changed since they were written.
+	encs := make(map[tempdb.BucketID][]byte)
+	sums := make(map[tempdb.BucketID][sha256.Size]byte)
+
+	var changed []tempdb.Bucket
+
+	for _, bkt := range puts {
+		v, err := db.cfg.marshal(&bkt)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(v)
+
+		if _, ok := db.records[bkt.ID]; ok && db.hashes[bkt.ID] == sum && !slices.Contains(dels, bkt.ID) {
+			continue
+		}
+
+		changed = append(changed, bkt)
+		encs[bkt.ID] = v
+		sums[bkt.ID] = sum
+	}
+
+	puts = changed
+
+	// skip writing when there is nothing to write.
+	if len(puts) == 0 && len(dels) == 0 {
+		return nil
+	}
+
+	// time the flush.
+	start := time.Now()
+	defer func() {
+		db.latency.add(time.Since(start))
+	}()
+
+	// the number of bytes of buckets written.
+	var n int
+
+	// tell the subscribers how the flush went.
+	db.emit(FlushEvent{Kind: FlushStarted})
+
+	defer func() {
+		if err != nil {
+			db.emit(FlushEvent{Kind: FlushFailed, Err: err})
+			return
+		}
+
+		db.emit(FlushEvent{Kind: FlushSucceeded, Bytes: n})
+	}()
+
+	// count the records once they are written.
+	count := len(db.records)
+
+	for _, id := range dels {
+		if _, ok := db.records[id]; ok {
+			count--
+		}
+	}
+
+	for _, bkt := range puts {
+		if _, ok := db.records[bkt.ID]; !ok || slices.Contains(dels, bkt.ID) {
+			count++
+		}
+	}
+
+	// split the puts into batches, by default everything is written at once.
+	size := db.cfg.batchSize
+
+	if size <= 0 || size > len(puts) {
+		size = max(len(puts), 1)
+	}
+
+	// track which top-level bucket each record belongs to.
+	rts := roots(db.State.Buckets)
+
+	// the path of every bucket, for the index.
+	var pths map[tempdb.BucketID]string
+
+	if db.cfg.index {
+		pths = paths(db.State.Buckets)
+	}
+
+	// shards belong to the top-level bucket and have the path of their bucket.
+	for _, bkt := range puts {
+		if id, ok := shardOf(bkt.ID); ok {
+			rts[bkt.ID] = rts[id]
+
+			if pths != nil {
+				pths[bkt.ID] = pths[id]
+			}
+		}
+	}
+
+	// encrypt every bucket, if configured.
+	vals := make(map[tempdb.BucketID][]byte)
+
+	// the plaintext records, by key.
+	plains := make(map[tempdb.BucketID]bool)
+
+	for _, bkt := range puts {
+		v, plain, err := db.cfg.encryptIn(encs[bkt.ID], rts[bkt.ID], recordData(bucketStore, bkt.ID))
+		if err != nil {
+			return err
+		}
+
+		vals[bkt.ID] = v
+		plains[bkt.ID] = plain
+	}
+
+	// index the keys of the buckets being written, if configured.
+	var keys map[tempdb.BucketID][]byte
+
+	if db.cfg.keyIndex {
+		keys, err = db.indexKeys(puts, rts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// stage the flush when it takes more than 1 write, writing it as 1 batch the backend stages, see `WithTwoPhaseFlush`.
+	var staged *idbBackend
+	var stageSize int
+
+	if db.cfg.twoPhase && size < len(puts) {
+		staged = indexedDB(db.backend)
+	}
+
+	if staged != nil {
+		stageSize, size = size, len(puts)
+	}
+
+	// journal the flush when it takes more than 1 write, so it can be replayed if it's interrupted.
+	journaled := db.cfg.journal && size < len(puts)
+
+	if journaled {
+		err := writeJournal(db.backend, &journal{
+			Buckets:   vals,
+			Deletes:   dels,
+			Count:     count,
+			Plaintext: formatKeys(db.plaintext(dels, puts, plains)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i == 0 || i < len(puts); i += size {
+		btch := puts[i:min(i+size, len(puts))]
+
+		ch := &Changes{
+			Records: Records{
+				Buckets: make(map[tempdb.BucketID][]byte),
+				Meta:    make(map[string][]byte),
+			},
+		}
+
+		// delete the records and index the keys in the first batch.
+		if i == 0 {
+			ch.Deletes = dels
+			ch.Keys = keys
+		}
+
+		for _, bkt := range btch {
+			ch.Buckets[bkt.ID] = vals[bkt.ID]
+
+			// store the records of blob buckets as blobs.
+			if db.cfg.blobs[rts[bkt.ID]] {
+				if ch.Blobs == nil {
+					ch.Blobs = make(map[tempdb.BucketID]bool)
+				}
+
+				ch.Blobs[bkt.ID] = true
+			}
+		}
+
+		// store the plaintext records with every batch, so they match the records written if a later batch fails.
+		plain := db.plaintext(ch.Deletes, btch, plains)
+
+		if db.cfg.aead != nil {
+			ch.Meta[plaintextKey] = formatKeys(plain)
+		}
+
+		// name every bucket for the index.
+		if db.cfg.index {
+			ch.Names = make(map[tempdb.BucketID]string)
+
+			for _, bkt := range btch {
+				ch.Names[bkt.ID], err = db.cfg.indexPath(pths[bkt.ID], rts[bkt.ID])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// store the count with the last batch, so a truncated load or an interrupted write can be detected.
+		if i+size >= len(puts) {
+			ch.Meta[countKey] = []byte(strconv.Itoa(count))
+
+			// store the data schema version the buckets were written with.
+			ch.Meta[schemaKey] = []byte(strconv.Itoa(schemaVersion))
+
+			// describe the layout of the buckets, if configured.
+			if db.cfg.descriptor {
+				ch.Meta[descriptorKey], err = db.cfg.encodeDescriptor(db.State.Buckets)
+				if err != nil {
+					return err
+				}
+			}
+
+			// store when every top-level bucket was last modified, merged and deleted, for the syncer and expiry.
+			if db.cfg.timed() {
+				for k, times := range map[string]map[string]time.Time{modifiedKey: db.modified, mergedKey: db.merged, tombstonesKey: db.tombstones} {
+					ch.Meta[k], err = db.cfg.encodeTimes(times)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			// clear the journal with the last batch, including one left by an earlier flush which failed.
+			if db.cfg.journal {
+				ch.Meta[journalKey] = []byte{}
+			}
+		}
+
+		if staged != nil {
+			err = staged.writeStaged(ch, stageSize)
+		} else {
+			err = db.backend.Write(ch)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ch.Deletes {
+			delete(db.records, id)
+			delete(db.hashes, id)
+
+			// the bucket is gone, unless it's put back in this batch.
+			if _, ok := ch.Buckets[id]; !ok {
+				delete(db.cfg.extra, id)
+				delete(db.cfg.sizes, id)
+			}
+		}
+
+		for _, bkt := range btch {
+			db.records[bkt.ID] = rts[bkt.ID]
+			db.hashes[bkt.ID] = sums[bkt.ID]
+
+			n += len(ch.Buckets[bkt.ID])
+		}
+
+		db.plain = plain
+	}
+
+	return nil
+}
+
+// get the keys of the plaintext records once the deletes and puts are written.
+func (db *DB) plaintext(dels []tempdb.BucketID, puts []tempdb.Bucket, plains map[tempdb.BucketID]bool) map[tempdb.BucketID]bool {
+	plain := maps.Clone(db.plain)
+
+	for _, id := range dels {
+		delete(plain, id)
+	}
+
+	for _, bkt := range puts {
+		if plains[bkt.ID] {
+			plain[bkt.ID] = true
+		} else {
+			delete(plain, bkt.ID)
+		}
+	}
+
+	return plain
+}
+
+func (db *DB) Close() error {
+	// keep the database open until every shared handle is closed.
+	if !db.release() {
+		return nil
+	}
+
+	db.unwatchOnline()
+
+	// flush the changes waiting for the database to be idle.
+	db.lock.Lock()
+	waiting := db.idleTimer != nil && db.idleTimer.Stop()
+	db.lock.Unlock()
+
+	if waiting {
+		err := db.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.backend.Close()
+}
+
+// encode a bucket to be stored, equal buckets always encode to the same bytes.
+func encode(bkt *tempdb.Bucket) ([]byte, error) {
+	// encode the bucket in its canonical form.
+	return gobCodec{}.encodeExtra(bkt, nil)
+}
+
+// decode a stored bucket.
+func decode(v []byte) (tempdb.Bucket, error) {
+	bkt, _, err := gobCodec{}.decodeExtra(v)
+	return bkt, err
+}
+
+// decode a gob value, returning an `ErrMalformedRecord` error instead of panicking on malformed input.
+func gobDecode(v []byte, e any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrMalformedRecord, r)
+		}
+	}()
+
+	err = gob.NewDecoder(bytes.NewReader(v)).Decode(e)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedRecord, err)
+	}
+
+	return nil
+}
+
 func newDB(create bool, args ...any) (*DB, error) {
 	// create the undelying tempDB database.
 	db, err := tempdb.New(args...)
@@ -121,114 +556,378 @@ func newDB(create bool, args ...any) (*DB, error) {
 	// cast the database to tempDB database.
 	tdb := db.(*tempdb.DB)
 
-	// wether or not the database existed before calling this function.
-	exist := true
+	// parse the options after the path.
+	opts, err := parseOptions(args[1:])
+	if err != nil {
+		return nil, err
+	}
 
-	// use the path as the database name.
-	idb, err := indexeddb.New(tdb.Path, 1, func(up *indexeddb.Upgrade) error {
-		// create the buckets store.
-		up.CreateStore(bucketStore)
+	cfg := newConfig(opts...)
 
-		exist = false
+	// ensure there is storage before creating the database.
+	if create {
+		err = checkQuota()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		return nil
-	})
+	// use the path as the database name.
+	b, exist, err := cfg.backend(tdb.Path)
 	if err != nil {
 		return nil, err
 	}
 
+	// ensure the database is stored in indexeddb, for the backups and staging.
+	if _, lazy := b.(*lazyBackend); (cfg.backupKeep > 0 || cfg.twoPhase) && indexedDB(b) == nil && !lazy {
+		b.Close()
+		return nil, ErrNotIndexedDB
+	}
+
 	// ensure the database did not already exist when creating.
 	if create && exist {
+		b.Close()
 		return nil, walletdb.ErrDbExists
 	}
 
 	// ensure the database exists when opening.
 	if !create && !exist {
+		b.Close()
 		return nil, walletdb.ErrDbDoesNotExist
 	}
 
-	return &DB{
-		idb: idb,
-		DB:  tdb,
-	}, nil
+	ldb := &DB{
+		backend: b,
+		DB:      tdb,
+
+		cfg: cfg,
+
+		records:    make(map[tempdb.BucketID]string),
+		hashes:     make(map[tempdb.BucketID][sha256.Size]byte),
+		plain:      make(map[tempdb.BucketID]bool),
+		modified:   make(map[string]time.Time),
+		merged:     make(map[string]time.Time),
+		tombstones: make(map[string]time.Time),
+		deferred:   make(map[string]bool),
+		queued:     make(map[string]bool),
+		dirty:      make(map[string]bool),
+	}
+
+	// count the backend's requests.
+	if tc, ok := b.(tripCounter); ok && cfg.roundTrips {
+		ldb.trips = &RoundTrips{}
+		tc.countTrips(ldb.trips)
+	}
+
+	// push the queued changes once the browser is back online.
+	if cfg.syncer != nil {
+		ldb.watchOnline()
+	}
+
+	// report the database being deleted.
+	if dn, ok := b.(deletionNotifier); ok && cfg.onDeleted != nil {
+		dn.notifyDeleted(cfg.onDeleted)
+	}
+
+	return ldb, nil
 }
 
 // create a new database.
 func New(args ...any) (walletdb.DB, error) {
-	return newDB(true, args...)
+	db, err := newDB(true, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// set up encryption, storing what's needed to check the key on open.
+	meta, err := db.cfg.encryption(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// store the mode, so the buckets are read as-is on open.
+	if db.cfg.strictUTF8 {
+		if meta == nil {
+			meta = make(map[string][]byte)
+		}
+
+		meta[rawKey] = []byte("1")
+	}
+
+	if meta != nil {
+		err = db.backend.Write(&Changes{
+			Records: Records{
+				Meta: meta,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db.share()
+
+	return db, nil
 }
 
 // open an existing database.
 func Open(args ...any) (walletdb.DB, error) {
+	// reuse the open handle, if it's shared.
+	if db := sharedHandle(args); db != nil {
+		return db, nil
+	}
+
+	// time every phase.
+	sw := newStopwatch()
+
 	db, err := newDB(false, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	// create a read transaction.
-	itx, err := db.idb.NewTransaction([]string{bucketStore}, indexeddb.ReadMode)
+	db.openTiming.Connect = sw.lap()
+
+	defer db.measure(&db.stats.Open)()
+
+	// get every stored record.
+	recs, err := db.loadWithin()
+	if err != nil {
+		return nil, err
+	}
+
+	db.openTiming.Load = sw.lap()
+
+	// ensure we have the key, if the database is encrypted.
+	_, err = db.cfg.encryption(recs.Meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure we can decode the buckets without losing data.
+	err = db.cfg.checkSchema(recs.Meta)
 	if err != nil {
 		return nil, err
 	}
 
-	// open the buckets store.
-	bkts := itx.Store(bucketStore)
+	// finish a flush that was interrupted.
+	err = replay(db.backend, recs)
+	if err != nil {
+		return nil, err
+	}
 
-	count, err := bkts.Count()
+	// find the records stored in plaintext.
+	db.plain, err = parseKeys(recs.Meta[plaintextKey])
 	if err != nil {
 		return nil, err
 	}
 
-	state := &tempdb.State{}
+	db.label = string(recs.Meta[labelKey])
 
-	for i := 0; i < count; i++ {
-		// get the encoded bucket.
-		val, err := bkts.Get(i)
+	// find when every top-level bucket was last modified and merged, and when the deleted buckets were deleted.
+	for k, times := range map[string]*map[string]time.Time{modifiedKey: &db.modified, mergedKey: &db.merged, tombstonesKey: &db.tombstones} {
+		if v := recs.Meta[k]; len(v) > 0 {
+			err = db.cfg.decodeTimes(v, times)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+
+	// forget the buckets deleted before the retention.
+	db.collect()
+
+	// ensure every stored bucket was loaded, databases from before the count was stored won't have one.
+	if v, ok := recs.Meta[countKey]; ok {
+		count, err := strconv.Atoi(string(v))
 		if err != nil {
 			return nil, err
 		}
 
-		// ensure the value is a string.
-		if t := val.Type(); t != js.TypeString {
-			return nil, fmt.Errorf("expected a type of %s: got %s", js.TypeString, t)
+		if count != len(recs.Buckets) {
+			return nil, fmt.Errorf("%w: expected %d, got %d", ErrCountMismatch, count, len(recs.Buckets))
 		}
+	}
+
+	db.openTiming.Prepare = sw.lap()
+
+	// sort the keys, so the buckets load in a stable order.
+	keys := make([]tempdb.BucketID, 0, len(recs.Buckets))
+
+	for key := range recs.Buckets {
+		keys = append(keys, key)
+	}
 
-		// unquote the string.
-		raw, err := strconv.Unquote(val.String())
+	slices.Sort(keys)
+
+	// decode every bucket.
+	bkts := make(map[tempdb.BucketID]tempdb.Bucket)
+
+	for _, key := range keys {
+		bkt, err := db.cfg.decodeRecord(key, recs.Buckets[key], db.plain)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("record %d: %w", key, err)
 		}
 
-		// create a reader for the encoded bucket.
-		r := strings.NewReader(raw)
+		// ensure the record encodes back to what's stored, if configured.
+		if db.cfg.paranoid {
+			err = db.cfg.recheck(key, recs.Buckets[key], db.plain[key], &bkt)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-		var bkt tempdb.Bucket
+		bkts[key] = bkt
+	}
 
-		// decode the bucket.
-		err = gob.NewDecoder(r).Decode(&bkt)
-		if err != nil {
-			return nil, err
+	// reassemble the sharded buckets.
+	shards, err := unshard(bkts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the loaded buckets.
+	var loaded []tempdb.Bucket
+
+	// the buckets stored under a key other than their ID, from when buckets were stored by index.
+	var stale []tempdb.Bucket
+
+	// the highest bucket ID.
+	var max tempdb.BucketID
+
+	for _, key := range keys {
+		bkt := bkts[key]
+
+		// the shards are merged into their bucket.
+		if slices.Contains(shards, key) {
+			continue
+		}
+
+		if key != bkt.ID {
+			// skip buckets which are also stored under their ID.
+			if other, ok := bkts[bkt.ID]; ok && other.ID == bkt.ID {
+				continue
+			}
+
+			// skip buckets we've already seen under another key.
+			if slices.ContainsFunc(stale, func(s tempdb.Bucket) bool { return s.ID == bkt.ID }) {
+				continue
+			}
+
+			stale = append(stale, bkt)
+		}
+
+		// IDs with the shard bits set are shard keys, a bucket with one is corrupt.
+		if _, ok := shardOf(bkt.ID); ok {
+			return nil, fmt.Errorf("record %d: %w: bucket ID %d", key, ErrMalformedRecord, bkt.ID)
 		}
 
-		// add the bucket to the state.
-		state.Buckets = append(state.Buckets, bkt)
+		if bkt.ID > max {
+			max = bkt.ID
+		}
+
+		loaded = append(loaded, bkt)
 	}
 
 	// update the database state.
-	*db.State = *state
+	*db.State = *newState(loaded, max)
+
+	db.recount()
+
+	// track which top-level bucket each record belongs to.
+	rts := roots(db.State.Buckets)
+
+	for key, bkt := range bkts {
+		db.records[key] = rts[owner(bkt.ID)]
+	}
+
+	db.openTiming.Decode = sw.lap()
+
+	// rewrite the stale buckets under their ID.
+	var dels []tempdb.BucketID
+
+	for key, bkt := range bkts {
+		if key != bkt.ID {
+			dels = append(dels, key)
+		}
+	}
+
+	err = db.write(stale, dels)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty the buckets which expired while the database was closed.
+	err = db.expire()
+	if err != nil {
+		return nil, err
+	}
+
+	db.openTiming.Rewrite = sw.lap()
+	db.openTiming.Total = sw.total()
+
+	db.share()
 
 	return db, nil
 }
 
+// open the database, creating it if it doesn't exist. created reports whether it was created.
+func OpenOrCreate(args ...any) (db walletdb.DB, created bool, err error) {
+	// create the database first, probing for a database which doesn't exist can create its storage.
+	db, err = New(args...)
+	if !errors.Is(err, walletdb.ErrDbExists) {
+		return db, err == nil, err
+	}
+
+	db, err = Open(args...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return db, false, nil
+}
+
+// the layout of `tempdb.State`, which doesn't expose the next bucket ID. remove it once tempdb has a setter,
+// `TestStateLayout` checks every field still matches.
+type stateLayout struct {
+	Buckets []tempdb.Bucket
+	next    tempdb.BucketID
+	nextTX  int
+}
+
+// ensure the layout is the size of `tempdb.State`, failing to compile if it changes.
+var _ [unsafe.Sizeof(tempdb.State{}) - unsafe.Sizeof(stateLayout{})]struct{}
+var _ [unsafe.Sizeof(stateLayout{}) - unsafe.Sizeof(tempdb.State{})]struct{}
+
+// create a state holding the buckets, max is the highest bucket ID.
+func newState(bkts []tempdb.Bucket, max tempdb.BucketID) *tempdb.State {
+	state := &tempdb.State{Buckets: bkts}
+
+	// continue allocating IDs after the highest stored one.
+	(*stateLayout)(unsafe.Pointer(state)).next = max
+
+	return state
+}
+
 func init() {
+	err := register()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// register the driver, ignoring a driver already registered as "localdb".
+// drivers aren't comparable, so it can't be checked to be ours. if another package registered it walletdb uses theirs,
+// and localdb databases can still be created and opened with `New` and `Open`.
+func register() error {
 	err := walletdb.RegisterDriver(walletdb.Driver{
 		DbType: "localdb",
 
 		Create: New,
 		Open:   Open,
 	})
-
-	if err != nil {
-		panic(err)
+	if errors.Is(err, walletdb.ErrDbTypeRegistered) {
+		return nil
 	}
+
+	return err
 }
@@ -4,12 +4,20 @@ package localdb
 
 import (
 	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcwallet/walletdb"
 	"github.com/btcsuite/btcwallet/walletdb/walletdbtest"
+	"github.com/linden/indexeddb"
 	"github.com/linden/tempdb"
 )
 
@@ -24,6 +32,115 @@ func TestInterface(t *testing.T) {
 	walletdbtest.TestInterface(t, "localdb", "test.db")
 }
 
+func TestRegister(t *testing.T) {
+	// the driver is registered on init, so registering it again must not fail.
+	err := register()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the driver still works.
+	if !slices.Contains(walletdb.SupportedDrivers(), "localdb") {
+		t.Fatal("expected the driver to be registered")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	// the name of the database.
+	nm := "batch-update.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Batch(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the batch was flushed.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("bucket"))
+		if bkt == nil {
+			t.Fatal("expected the bucket to be stored")
+		}
+
+		if v := bkt.Get([]byte("key")); string(v) != "value" {
+			t.Fatalf("expected value: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	db, err := walletdb.Create("localdb", "copy-source.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+
+	err = db.Copy(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// restore the copy.
+	err = ImportFrom("copy-destination.db", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpy, err := walletdb.Open("localdb", "copy-destination.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(cpy, func(tx walletdb.ReadTx) error {
+		if v := tx.ReadBucket([]byte("bucket")).Get([]byte("key")); string(v) != "value" {
+			t.Fatalf("expected value: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the stats are implemented.
+	if st := db.PrintStats(); !strings.Contains(st, "buckets: 1, keys: 1") {
+		t.Fatalf("expected the stats to count the bucket: got %s", st)
+	}
+}
+
 func TestPersistence(t *testing.T) {
 	// the name of the database.
 	nm := "persistence.db"
@@ -85,3 +202,555 @@ func TestPersistence(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReopen(t *testing.T) {
+	// the name of the database.
+	nm := "reopen.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the names of the buckets, each created after reopening.
+	nms := [][]byte{
+		[]byte("first"),
+		[]byte("second"),
+		[]byte("third"),
+	}
+
+	for _, bktNm := range nms {
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt, err := tx.CreateTopLevelBucket(bktNm)
+			if err != nil {
+				return err
+			}
+
+			return bkt.Put(bktNm, bktNm)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, err = walletdb.Open("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for _, bktNm := range nms {
+			bkt := tx.ReadBucket(bktNm)
+			if bkt == nil {
+				t.Fatalf("expected bucket %s to exist", bktNm)
+			}
+
+			if v := bkt.Get(bktNm); !bytes.Equal(v, bktNm) {
+				t.Fatalf("expected %s but got %s", bktNm, v)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCountMismatch(t *testing.T) {
+	// the name of the database.
+	nm := "count.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// open a second connection to tamper with the metadata.
+	idb, err := indexeddb.New(nm, version<<16, func(up *indexeddb.Upgrade) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itx, err := idb.NewTransaction([]string{metaStore}, indexeddb.ReadWriteMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// claim more buckets were stored than exist.
+	err = itx.Store(metaStore).Put(countKey, quote([]byte("2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrCountMismatch) {
+		t.Fatalf("expected %v: got %v", ErrCountMismatch, err)
+	}
+}
+
+func TestSchemaTooNew(t *testing.T) {
+	// the name of the database.
+	nm := "schema.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// store a newer schema, as if a newer version of localdb wrote the database.
+	err = db.(*DB).backend.Write(&Changes{
+		Records: Records{
+			Meta: map[string][]byte{
+				schemaKey: []byte(fmt.Sprint(schemaVersion + 1)),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected %v: got %v", ErrSchemaTooNew, err)
+	}
+
+	// ensure the database opens when forced.
+	db, err = walletdb.Open("localdb", nm, WithForceSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if tx.ReadBucket([]byte("bucket")) == nil {
+			t.Fatal("expected the bucket to exist")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a value which panics when it's decoded.
+type panicky struct{}
+
+func (panicky) GobEncode() ([]byte, error) {
+	return []byte("panic"), nil
+}
+
+func (*panicky) GobDecode([]byte) error {
+	panic("malformed")
+}
+
+func TestMalformedRecord(t *testing.T) {
+	// ensure a decode which panics is recovered.
+	buf := new(bytes.Buffer)
+
+	err := gob.NewEncoder(buf).Encode(panicky{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gobDecode(buf.Bytes(), &panicky{})
+	if !errors.Is(err, ErrMalformedRecord) {
+		t.Fatalf("expected %v: got %v", ErrMalformedRecord, err)
+	}
+
+	// the name of the database.
+	nm := "malformed.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// store malformed gob as the bucket's record.
+	id := db.(*DB).State.Buckets[0].ID
+
+	err = db.(*DB).backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{
+				id: {0x03, 0xff, 0x81, 0x03, 0x01},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure open fails with an error naming the record.
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrMalformedRecord) {
+		t.Fatalf("expected %v: got %v", ErrMalformedRecord, err)
+	}
+
+	if exp := fmt.Sprintf("record %d:", id); !strings.HasPrefix(err.Error(), exp) {
+		t.Fatalf("expected the error to name the record: got %v", err)
+	}
+}
+
+func TestLargeBucketID(t *testing.T) {
+	nm := "large-id.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "bucket", "value")
+
+	bkt := db.(*DB).State.Buckets[0]
+
+	db.Close()
+
+	// store the bucket with the ID under the key.
+	store := func(key, id tempdb.BucketID) {
+		b, _, err := IndexedDB(nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer b.Close()
+
+		old := bkt.ID
+
+		bkt.ID = id
+
+		v, err := encode(&bkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = b.Write(&Changes{
+			Records: Records{
+				Buckets: map[tempdb.BucketID][]byte{key: v},
+			},
+			Deletes: []tempdb.BucketID{old},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure the state isn't filled up to the ID.
+	store(1<<40, 1<<40)
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "bucket"); v != "value" {
+		t.Fatalf("expected value: got %q", v)
+	}
+
+	// ensure new buckets are allocated after it.
+	putValue(t, db, "other", "value")
+
+	id, _ := topLevel(db.(*DB).State.Buckets, []byte("other"))
+	if id != 1<<40+1 {
+		t.Fatalf("expected ID %d: got %d", 1<<40+1, id)
+	}
+
+	// remove the other bucket, so only the moved bucket is stored.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket([]byte("other"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure a bucket ID with the shard bits set is rejected.
+	store(1<<40, 1<<shardShift|1)
+
+	_, err = walletdb.Open("localdb", nm)
+	if !errors.Is(err, ErrMalformedRecord) {
+		t.Fatalf("expected %v: got %v", ErrMalformedRecord, err)
+	}
+}
+
+func TestStateLayout(t *testing.T) {
+	// ensure every field of the layout matches `tempdb.State`, not only the size.
+	exp := reflect.TypeOf(tempdb.State{})
+	got := reflect.TypeOf(stateLayout{})
+
+	if got.NumField() != exp.NumField() {
+		t.Fatalf("expected %d fields: got %d", exp.NumField(), got.NumField())
+	}
+
+	for i := 0; i < exp.NumField(); i++ {
+		ef, gf := exp.Field(i), got.Field(i)
+
+		if gf.Name != ef.Name || gf.Type != ef.Type || gf.Offset != ef.Offset {
+			t.Fatalf("expected field %d to be %s %s at %d: got %s %s at %d", i, ef.Name, ef.Type, ef.Offset, gf.Name, gf.Type, gf.Offset)
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	// the name of the database.
+	nm := "upgrade.db"
+
+	// create a database in the first version, which stored buckets by index.
+	idb, err := indexeddb.New(nm, 1, func(up *indexeddb.Upgrade) error {
+		up.CreateStore(bucketStore)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bkt := tempdb.Bucket{
+		ID:  1,
+		Key: []byte("bucket"),
+		Value: map[string][]byte{
+			"key": []byte("value"),
+		},
+	}
+
+	v, err := encode(&bkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itx, err := idb.NewTransaction([]string{bucketStore}, indexeddb.ReadWriteMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = itx.Store(bucketStore).Put(0, quote(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// close the connection so the database can be upgraded.
+	idb.Close()
+
+	// open the database twice, once to upgrade it and once to read the upgraded database.
+	for i := 0; i < 2; i++ {
+		db, err := walletdb.Open("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			bkt := tx.ReadBucket([]byte("bucket"))
+			if bkt == nil {
+				t.Fatal("expected the bucket to exist")
+			}
+
+			if v := bkt.Get([]byte("key")); !bytes.Equal(v, []byte("value")) {
+				t.Fatalf("expected value but got %s", v)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db.Close()
+	}
+}
+
+func TestDeterministicEncoding(t *testing.T) {
+	bkt := tempdb.Bucket{
+		ID:    1,
+		Key:   []byte("bucket"),
+		Value: make(map[string][]byte),
+	}
+
+	for i := 0; i < 100; i++ {
+		bkt.Value[fmt.Sprint(i)] = []byte(fmt.Sprint(i))
+	}
+
+	v, err := encode(&bkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the bucket encodes to the same bytes every time, despite the map order.
+	for i := 0; i < 10; i++ {
+		ev, err := encode(&bkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(ev, v) {
+			t.Fatal("expected the bucket to encode to the same bytes")
+		}
+	}
+
+	// ensure buckets encoded before the canonical form still decode.
+	buf := new(bytes.Buffer)
+
+	err = gob.NewEncoder(buf).Encode(&bkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range [][]byte{v, buf.Bytes()} {
+		dbkt, err := decode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !equal(&dbkt, &bkt) {
+			t.Fatalf("expected %v: got %v", bkt, dbkt)
+		}
+	}
+}
+
+// a backend which takes a while to load.
+type slowBackend struct {
+	Backend
+	delay time.Duration
+}
+
+func (b *slowBackend) Load() (*Records, error) {
+	time.Sleep(b.delay)
+	return b.Backend.Load()
+}
+
+func TestOpenTimeout(t *testing.T) {
+	// the name of the database.
+	nm := "timeout.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	slow := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return &slowBackend{Backend: b, delay: 200 * time.Millisecond}, exist, nil
+	}
+
+	_, err = walletdb.Open("localdb", nm, WithBackend(slow), WithOpenTimeout(10*time.Millisecond))
+	if !errors.Is(err, ErrOpenTimeout) {
+		t.Fatalf("expected %v: got %v", ErrOpenTimeout, err)
+	}
+
+	// ensure a fast enough load opens.
+	db, err = walletdb.Open("localdb", nm, WithOpenTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+}
+
+func TestSharedHandle(t *testing.T) {
+	// the name of the database.
+	nm := "handle.db"
+
+	db, err := walletdb.Create("localdb", nm, WithSharedHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := walletdb.Open("localdb", nm, WithSharedHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure a write through one handle is seen through the other.
+	putValue(t, db, "bucket", "value")
+
+	if v := getValue(t, other, "bucket"); v != "value" {
+		t.Fatalf("expected value: got %q", v)
+	}
+
+	// ensure the database stays open until both handles are closed.
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, other, "bucket", "changed")
+
+	err = other.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the last handle closed the database, so it's stored and can be opened again.
+	db, err = walletdb.Open("localdb", nm, WithSharedHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if db == other {
+		t.Fatal("expected a new handle once every handle was closed")
+	}
+
+	if v := getValue(t, db, "bucket"); v != "changed" {
+		t.Fatalf("expected changed: got %q", v)
+	}
+}
+
+func TestOpenOrCreate(t *testing.T) {
+	nm := "openorcreate.db"
+
+	db, created, err := OpenOrCreate(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !created {
+		t.Fatal("expected the database to be created")
+	}
+
+	putValue(t, db, "bucket", "value")
+	db.Close()
+
+	// ensure the existing database is opened.
+	db, created, err = OpenOrCreate(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if created {
+		t.Fatal("expected the database to be opened")
+	}
+
+	if v := getValue(t, db, "bucket"); v != "value" {
+		t.Fatalf("expected value: got %q", v)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// the browser is offline, so the syncer can't be reached.
+var ErrOffline = errors.New("browser is offline")
+
+// check if the browser is online, it's a variable so it can be replaced in tests.
+// browsers which don't report it are assumed to be online.
+var online = func() bool {
+	v := js.Global().Get("navigator").Get("onLine")
+	return v.Type() != js.TypeBoolean || v.Bool()
+}
+
+// resume pushing once the browser is back online, see `WithSyncer`.
+func (db *DB) watchOnline() {
+	db.onOnline = js.FuncOf(func(this js.Value, args []js.Value) any {
+		// push outside the event handler, the syncer may block.
+		go db.resume()
+
+		return nil
+	})
+
+	js.Global().Call("addEventListener", "online", db.onOnline)
+}
+
+// stop watching for the browser going online, if it's watched.
+func (db *DB) unwatchOnline() {
+	if db.onOnline.IsUndefined() {
+		return
+	}
+
+	js.Global().Call("removeEventListener", "online", db.onOnline)
+
+	db.onOnline.Release()
+	db.onOnline = js.Func{}
+}
+
+// push the changes queued while the browser was offline.
+func (db *DB) resume() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if len(db.queued) == 0 || !online() {
+		return
+	}
+
+	// the changes stay queued if the push fails, so they're pushed again with the next commit.
+	db.push(nil)
+}
@@ -0,0 +1,65 @@
+//go:build js && wasm
+
+package localdb
+
+import "time"
+
+// how long each phase of opening the database took, see `OpenTiming`.
+type OpenTiming struct {
+	// opening the backend, such as connecting to indexeddb and upgrading it.
+	Connect time.Duration
+
+	// reading every stored record.
+	Load time.Duration
+
+	// checking the records before they're decoded, deriving the key, checking the schema and the count and finishing
+	// an interrupted flush.
+	Prepare time.Duration
+
+	// decoding every record into the in-memory state.
+	Decode time.Duration
+
+	// rewriting stale records and emptying expired buckets.
+	Rewrite time.Duration
+
+	// the whole open, the phases add up to it.
+	Total time.Duration
+}
+
+// get how long each phase of opening the database took, so a slow startup can be attributed.
+// databases which were created rather than opened have no timing.
+func (db *DB) OpenTiming() OpenTiming {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.openTiming
+}
+
+// times consecutive phases.
+type stopwatch struct {
+	start time.Time
+	last  time.Time
+}
+
+func newStopwatch() *stopwatch {
+	now := time.Now()
+
+	return &stopwatch{
+		start: now,
+		last:  now,
+	}
+}
+
+// get the time since the last phase ended, ending the current phase.
+func (s *stopwatch) lap() time.Duration {
+	now := time.Now()
+	d := now.Sub(s.last)
+	s.last = now
+
+	return d
+}
+
+// get the time since the stopwatch started.
+func (s *stopwatch) total() time.Duration {
+	return s.last.Sub(s.start)
+}
@@ -0,0 +1,49 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestOpenTiming(t *testing.T) {
+	// the name of the database.
+	nm := "open-timing.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for i := 0; i < 100; i++ {
+			_, err := tx.CreateTopLevelBucket([]byte(fmt.Sprintf("bucket-%d", i)))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm := db.(*DB).OpenTiming()
+
+	// connecting and loading wait on indexeddb, so they always take time.
+	if tm.Connect <= 0 || tm.Load <= 0 {
+		t.Fatalf("expected the connect and load phases to be timed: got %+v", tm)
+	}
+
+	if sum := tm.Connect + tm.Load + tm.Prepare + tm.Decode + tm.Rewrite; sum != tm.Total {
+		t.Fatalf("expected the phases to add up to %s: got %s", tm.Total, sum)
+	}
+}
@@ -0,0 +1,291 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"strconv"
+	"syscall/js"
+
+	"github.com/linden/tempdb"
+)
+
+// the directory every database is stored under.
+const opfsDir = "localdb"
+
+// the origin private file system is not supported.
+var ErrOPFSUnsupported = errors.New("origin private file system is not supported")
+
+// Ensure `opfsBackend` complies with the `Backend` interface.
+var _ Backend = (*opfsBackend)(nil)
+
+// the origin private file system backend stores each record as a file.
+// synchronous access handles are used where available, which is only in dedicated workers.
+//
+// compared to indexeddb:
+//   - there's no quota per record and large files are cheap to read and write.
+//   - writes are not atomic, a crash during a write can leave some files updated and others not.
+//   - a file can only have 1 synchronous access handle open, so the database can only be open in 1 worker at a time.
+type opfsBackend struct {
+	// the directories for the buckets and metadata.
+	buckets js.Value
+	meta    js.Value
+}
+
+func (b *opfsBackend) Load() (*Records, error) {
+	recs := &Records{
+		Buckets: make(map[tempdb.BucketID][]byte),
+		Meta:    make(map[string][]byte),
+	}
+
+	// read every bucket.
+	err := readDir(b.buckets, func(name string, v []byte) error {
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		recs.Buckets[tempdb.BucketID(id)] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// read every metadata value.
+	err = readDir(b.meta, func(name string, v []byte) error {
+		recs.Meta[name] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+func (b *opfsBackend) Write(ch *Changes) error {
+	for _, id := range ch.Deletes {
+		_, err := await(b.buckets.Call("removeEntry", strconv.FormatUint(uint64(id), 10)))
+
+		// ignore files that don't exist.
+		if err != nil && !notFound(err) {
+			return err
+		}
+	}
+
+	for k, v := range ch.Meta {
+		err := writeFile(b.meta, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id, v := range ch.Buckets {
+		err := writeFile(b.buckets, strconv.FormatUint(uint64(id), 10), v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *opfsBackend) Close() error {
+	return nil
+}
+
+// OPFS stores the database in the origin private file system, in a directory named after the database.
+func OPFS(name string) (Backend, bool, error) {
+	strg := js.Global().Get("navigator").Get("storage")
+
+	// ensure the origin private file system is supported.
+	if strg.IsUndefined() || strg.Get("getDirectory").IsUndefined() {
+		return nil, false, ErrOPFSUnsupported
+	}
+
+	// get the root directory.
+	root, err := await(strg.Call("getDirectory"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	// get the directory for every database.
+	root, err = directory(root, opfsDir, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// check if the database already exists.
+	exist := true
+
+	_, err = directory(root, name, false)
+	if notFound(err) {
+		exist = false
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	// get the directory for the database, creating it if it doesn't exist.
+	dir, err := directory(root, name, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	bkts, err := directory(dir, bucketStore, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	meta, err := directory(dir, metaStore, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &opfsBackend{
+		buckets: bkts,
+		meta:    meta,
+	}, exist, nil
+}
+
+// get a directory within a directory.
+func directory(dir js.Value, name string, create bool) (js.Value, error) {
+	opts := js.Global().Get("Object").New()
+	opts.Set("create", create)
+
+	return await(dir.Call("getDirectoryHandle", name, opts))
+}
+
+// check if an error is because a file or directory was not found.
+func notFound(err error) bool {
+	return isJSError(err, "NotFoundError")
+}
+
+// read every file in a directory.
+func readDir(dir js.Value, fn func(name string, v []byte) error) error {
+	// iterate over the names of the files.
+	iter := dir.Call("keys")
+
+	for {
+		res, err := await(iter.Call("next"))
+		if err != nil {
+			return err
+		}
+
+		if res.Get("done").Bool() {
+			return nil
+		}
+
+		name := res.Get("value").String()
+
+		v, err := readFile(dir, name)
+		if err != nil {
+			return err
+		}
+
+		err = fn(name, v)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// read a file.
+func readFile(dir js.Value, name string) ([]byte, error) {
+	fh, err := await(dir.Call("getFileHandle", name))
+	if err != nil {
+		return nil, err
+	}
+
+	// use a synchronous access handle if we can.
+	if fh.Get("createSyncAccessHandle").Type() == js.TypeFunction {
+		ah, err := await(fh.Call("createSyncAccessHandle"))
+		if err != nil {
+			return nil, err
+		}
+
+		defer ah.Call("close")
+
+		// read the whole file.
+		arr := js.Global().Get("Uint8Array").New(ah.Call("getSize"))
+		ah.Call("read", arr, at(0))
+
+		return copyBytes(arr), nil
+	}
+
+	// fallback to reading the file asynchronously.
+	f, err := await(fh.Call("getFile"))
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := await(f.Call("arrayBuffer"))
+	if err != nil {
+		return nil, err
+	}
+
+	return copyBytes(js.Global().Get("Uint8Array").New(buf)), nil
+}
+
+// write a file, creating it if it doesn't exist.
+func writeFile(dir js.Value, name string, v []byte) error {
+	opts := js.Global().Get("Object").New()
+	opts.Set("create", true)
+
+	fh, err := await(dir.Call("getFileHandle", name, opts))
+	if err != nil {
+		return err
+	}
+
+	// copy the value into javascript.
+	arr := js.Global().Get("Uint8Array").New(len(v))
+	js.CopyBytesToJS(arr, v)
+
+	// use a synchronous access handle if we can.
+	if fh.Get("createSyncAccessHandle").Type() == js.TypeFunction {
+		ah, err := await(fh.Call("createSyncAccessHandle"))
+		if err != nil {
+			return err
+		}
+
+		defer ah.Call("close")
+
+		// replace the contents of the file.
+		ah.Call("truncate", 0)
+		ah.Call("write", arr, at(0))
+		ah.Call("flush")
+
+		return nil
+	}
+
+	// fallback to writing the file asynchronously.
+	w, err := await(fh.Call("createWritable"))
+	if err != nil {
+		return err
+	}
+
+	_, err = await(w.Call("write", arr))
+	if err != nil {
+		w.Call("abort")
+		return err
+	}
+
+	_, err = await(w.Call("close"))
+	return err
+}
+
+// create the options to read or write at an offset.
+func at(offset int) js.Value {
+	opts := js.Global().Get("Object").New()
+	opts.Set("at", offset)
+
+	return opts
+}
+
+// copy a `Uint8Array` into Go.
+func copyBytes(arr js.Value) []byte {
+	v := make([]byte, arr.Length())
+	js.CopyBytesToGo(v, arr)
+
+	return v
+}
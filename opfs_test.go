@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"syscall/js"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestOPFS(t *testing.T) {
+	// ensure the origin private file system is available.
+	strg := js.Global().Get("navigator").Get("storage")
+	if strg.IsUndefined() || strg.Get("getDirectory").IsUndefined() {
+		t.Skip("origin private file system is not supported")
+	}
+
+	// synchronous access handles are only available in workers.
+	if js.Global().Get("FileSystemSyncAccessHandle").IsUndefined() {
+		t.Log("not in a worker, using asynchronous access")
+	}
+
+	// the name of the database.
+	nm := "opfs.db"
+	db, err := walletdb.Create("localdb", nm, WithBackend(OPFS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the names of the buckets.
+	nms := [][]byte{
+		[]byte("first"),
+		[]byte("second"),
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, bktNm := range nms {
+			bkt, err := tx.CreateTopLevelBucket(bktNm)
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put(bktNm, bktNm)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// delete a bucket, so its file is removed.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket(nms[1])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the database can't be created twice.
+	_, err = walletdb.Create("localdb", nm, WithBackend(OPFS))
+	if err != walletdb.ErrDbExists {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbExists, err)
+	}
+
+	db, err = walletdb.Open("localdb", nm, WithBackend(OPFS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket(nms[0])
+		if bkt == nil {
+			t.Fatalf("expected bucket %s to exist", nms[0])
+		}
+
+		if v := bkt.Get(nms[0]); !bytes.Equal(v, nms[0]) {
+			t.Fatalf("expected %s but got %s", nms[0], v)
+		}
+
+		if tx.ReadBucket(nms[1]) != nil {
+			t.Fatalf("expected bucket %s to be deleted", nms[1])
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
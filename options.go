@@ -0,0 +1,244 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"time"
+
+	"github.com/linden/tempdb"
+)
+
+// an option configures a database, options are passed after the path.
+//
+//	walletdb.Create("localdb", "wallet.db", localdb.WithBackend(localdb.CacheStorage))
+type Option func(cfg *config)
+
+type config struct {
+	// creates the backend.
+	backend BackendFunc
+
+	// the number of buckets written per transaction, 0 writes every bucket at once.
+	batchSize int
+
+	// encodes the stored buckets.
+	codec Codec
+
+	// the encryption key, or the passphrase to derive it from.
+	key        []byte
+	passphrase string
+
+	// encrypts the stored buckets, once the key is known.
+	aead cipher.AEAD
+
+	// transforms bucket names before they're stored, and reverses it when they're loaded.
+	name    func(name []byte) []byte
+	inverse func(name []byte) ([]byte, error)
+
+	// how long to wait for commits to stop before flushing, 0 flushes every commit.
+	idle time.Duration
+
+	// write a snapshot every nth commit, keeping the last `backupKeep`, see `WithAutoBackup`.
+	backupEvery int
+	backupKeep  int
+
+	// whether to journal flushes that take more than 1 write.
+	journal bool
+
+	// whether to stage flushes that take more than 1 write, see `WithTwoPhaseFlush`.
+	twoPhase bool
+
+	// whether to open databases with a newer data schema.
+	forceSchema bool
+
+	// which top-level buckets to encrypt, nil encrypts every bucket.
+	encryptBucket func(name []byte) bool
+
+	// pushes commits to a remote, see `WithSyncer`.
+	syncer Syncer
+
+	// merges conflicting changes key by key, see `WithResolver`.
+	resolver Resolver
+
+	// stores the bucket records as-is, see `WithStrictUTF8`.
+	strictUTF8 bool
+
+	// checks the values put in the top-level buckets, see `WithValidator`.
+	validators map[string]func(key, value []byte) error
+
+	// called when the database is deleted while it's open, see `WithOnDeleted`.
+	onDeleted func()
+
+	// shares the handle between opens, see `WithSharedHandle`.
+	sharedHandle bool
+
+	// how long the tombstones of deleted top-level buckets are kept, see `WithTombstoneRetention`.
+	tombstoneRetention time.Duration
+
+	// the most top-level buckets, see `WithMaxBuckets`.
+	maxBuckets int
+
+	// the database to convert and the options to read it with, see `WithSource`.
+	source     string
+	sourceOpts []Option
+
+	// gets the current time, see `WithClock`.
+	now func() time.Time
+
+	// how long every expiring top-level bucket is kept after it's modified, see `WithBucketTTL`.
+	ttls map[string]time.Duration
+
+	// the number of keys a bucket is split into records by key above, see `WithSharding`.
+	shardKeys int
+
+	// how long loading the database can take when opening it, see `WithOpenTimeout`.
+	openTimeout time.Duration
+
+	// counts the indexeddb requests, see `WithRoundTrips`.
+	roundTrips bool
+
+	// the opaque extra data of the decoded records from newer versions, by bucket ID, see `canonical`.
+	extra map[tempdb.BucketID]map[string][]byte
+
+	// the size of every bucket's last encoding, by bucket ID, so the next is preallocated.
+	sizes map[tempdb.BucketID]int
+
+	// whether to store the bucket index.
+	index bool
+
+	// whether to store the key index, see `WithKeyIndex`.
+	keyIndex bool
+
+	// compresses the bucket records above the size, see `WithCompression` and `WithMinCompressSize`.
+	compression bool
+	minCompress int
+
+	// the top-level buckets whose records are stored as blobs, see `WithBlobBuckets`.
+	blobs map[string]bool
+
+	// re-encodes the records decoded on open, see `WithParanoidChecks`.
+	paranoid bool
+
+	// whether to store the schema descriptor and the encodings it describes, by path, see `WithSchemaDescriptor`.
+	descriptor bool
+	encodings  map[string]string
+
+	// the number of times a failed load is retried, and the delay between attempts.
+	readRetries int
+	readDelay   time.Duration
+}
+
+// limit the number of top-level buckets, so `CreateTopLevelBucket` fails with `ErrTooManyBuckets` once there are n.
+// it guards against code creating buckets without bound, the buckets created by `ImportBuckets`, `CopyBucket` and
+// `Pull` count too. `ImportFrom` writes the stored records directly, so it isn't limited.
+func WithMaxBuckets(n int) Option {
+	return func(cfg *config) {
+		cfg.maxBuckets = n
+	}
+}
+
+// store the database using a backend other than indexeddb.
+func WithBackend(fn BackendFunc) Option {
+	return func(cfg *config) {
+		cfg.backend = fn
+	}
+}
+
+// split flushes into transactions of at most size buckets, instead of writing every bucket in 1 transaction.
+// large flushes are faster and less likely to be closed by the browser, but a flush is no longer atomic:
+// if it's interrupted some batches are written and others are not. the bucket count is written with the last batch,
+// so an interrupted flush is detected by `Open` and can be salvaged with `Repair`.
+func WithBatchSize(size int) Option {
+	return func(cfg *config) {
+		cfg.batchSize = size
+	}
+}
+
+// encode buckets with a codec other than gob.
+// records written with another codec are still read, so buckets migrate to the codec as they're written.
+func WithCodec(c Codec) Option {
+	return func(cfg *config) {
+		cfg.codec = c
+	}
+}
+
+// store an index of bucket names alongside the buckets, so records can be identified from the browser's devtools.
+// the index maps every bucket ID to its path, such as "parent/child". only the indexeddb backend stores it,
+// in the "bucket_index" object store. the paths of encrypted buckets are encrypted and hex encoded.
+func WithBucketIndex() Option {
+	return func(cfg *config) {
+		cfg.index = true
+	}
+}
+
+// transform the name of every bucket before it's stored, such as to namespace them with a prefix.
+// inverse must reverse fn, it's used when loading and should fail for names fn didn't produce,
+// so a database stored with another transform fails to open. the app only sees the names it used,
+// exports are also untransformed.
+func WithBucketNameTransform(fn func(name []byte) []byte, inverse func(name []byte) ([]byte, error)) Option {
+	return func(cfg *config) {
+		cfg.name = fn
+		cfg.inverse = inverse
+	}
+}
+
+// retry loading the database up to attempts times when it fails, waiting delay between attempts.
+// loading only reads, so it's safe to retry. writes are not retried, since a failed write may have been partially applied.
+func WithReadRetry(attempts int, delay time.Duration) Option {
+	return func(cfg *config) {
+		cfg.readRetries = attempts
+		cfg.readDelay = delay
+	}
+}
+
+// get the current time from fn instead of `time.Now`, for the times localdb records such as when buckets are
+// modified and when exports are created. this makes the times deterministic in tests.
+func WithClock(fn func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.now = fn
+	}
+}
+
+// create the config from the options.
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		backend: IndexedDB,
+		codec:   Gob,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// get the options from the arguments after the path.
+func parseOptions(args []any) ([]Option, error) {
+	var opts []Option
+
+	for _, arg := range args {
+		opt, ok := arg.(Option)
+		if !ok {
+			return nil, fmt.Errorf("argument is not an option: %T", arg)
+		}
+
+		opts = append(opts, opt)
+	}
+
+	return opts, nil
+}
+
+// load every record from the backend, retrying if configured.
+func (cfg *config) load(b Backend) (*Records, error) {
+	for i := 0; ; i++ {
+		recs, err := b.Load()
+		if err == nil || i >= cfg.readRetries {
+			return recs, err
+		}
+
+		time.Sleep(cfg.readDelay)
+	}
+}
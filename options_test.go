@@ -0,0 +1,310 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// a backend which counts the writes, and the buckets put.
+type countingBackend struct {
+	Backend
+	writes int
+	puts   int
+}
+
+func (b *countingBackend) Write(ch *Changes) error {
+	b.writes++
+	b.puts += len(ch.Buckets)
+
+	return b.Backend.Write(ch)
+}
+
+// wrap the indexeddb backend, so we can count the writes.
+func counting(cb **countingBackend) BackendFunc {
+	return func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		*cb = &countingBackend{Backend: b}
+		return *cb, exist, nil
+	}
+}
+
+// a backend which fails to load a number of times.
+type flakyBackend struct {
+	Backend
+	failures int
+	loads    int
+}
+
+func (b *flakyBackend) Load() (*Records, error) {
+	b.loads++
+
+	if b.loads <= b.failures {
+		return nil, errors.New("transient failure")
+	}
+
+	return b.Backend.Load()
+}
+
+func TestBatchSize(t *testing.T) {
+	// the name of the database.
+	nm := "batch.db"
+
+	var cb *countingBackend
+
+	db, err := walletdb.Create("localdb", nm, WithBackend(counting(&cb)), WithBatchSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the number of buckets, more than the batch size.
+	n := 5
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for i := 0; i < n; i++ {
+			_, err := tx.CreateTopLevelBucket([]byte(fmt.Sprint(i)))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the flush was split into 3 batches of up to 2 buckets.
+	if cb.writes != 3 {
+		t.Fatalf("expected 3 writes: got %d", cb.writes)
+	}
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for i := 0; i < n; i++ {
+			if tx.ReadBucket([]byte(fmt.Sprint(i))) == nil {
+				t.Fatalf("expected bucket %d to exist", i)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadRetry(t *testing.T) {
+	// the name of the database.
+	nm := "retry.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fb *flakyBackend
+
+	// wrap the indexeddb backend, so the first 2 loads fail.
+	flaky := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		fb = &flakyBackend{Backend: b, failures: 2}
+		return fb, exist, nil
+	}
+
+	// ensure opening fails without retries.
+	_, err = walletdb.Open("localdb", nm, WithBackend(flaky))
+	if err == nil {
+		t.Fatal("expected a flaky load to fail without retries")
+	}
+
+	db, err = walletdb.Open("localdb", nm, WithBackend(flaky), WithReadRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fb.loads != 3 {
+		t.Fatalf("expected 3 loads: got %d", fb.loads)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if tx.ReadBucket([]byte("bucket")) == nil {
+			t.Fatal("expected the bucket to exist")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBucketNameTransform(t *testing.T) {
+	// the name of the database.
+	nm := "transform.db"
+
+	// namespace every bucket with a tenant prefix.
+	tenant := func(prefix string) Option {
+		return WithBucketNameTransform(func(name []byte) []byte {
+			return append([]byte(prefix), name...)
+		}, func(name []byte) ([]byte, error) {
+			if !bytes.HasPrefix(name, []byte(prefix)) {
+				return nil, fmt.Errorf("bucket %s is not in %s", name, prefix)
+			}
+
+			return name[len(prefix):], nil
+		})
+	}
+
+	db, err := walletdb.Create("localdb", nm, tenant("a/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		_, err = bkt.CreateBucket([]byte("child"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the stored names are namespaced.
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range recs.Buckets {
+		bkt, err := decode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.HasPrefix(bkt.Key, []byte("a/")) {
+			t.Fatalf("expected %s to be namespaced", bkt.Key)
+		}
+	}
+
+	// ensure the app sees the untransformed names.
+	db, err = walletdb.Open("localdb", nm, tenant("a/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("parent"))
+		if bkt == nil || bkt.NestedReadBucket([]byte("child")) == nil {
+			t.Fatal("expected the buckets to exist under their untransformed names")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure another namespace can't open the database.
+	_, err = walletdb.Open("localdb", nm, tenant("b/"))
+	if err == nil {
+		t.Fatal("expected opening with another namespace to fail")
+	}
+}
+
+func TestClock(t *testing.T) {
+	clock := time.UnixMilli(1_700_000_000_000)
+
+	s := &memorySyncer{}
+
+	db, err := walletdb.Create("localdb", "clock.db", WithSyncer(s), WithClock(func() time.Time { return clock }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	putValue(t, db, "bucket", "value")
+
+	// ensure the modified time is from the clock.
+	if len(s.pushed) != 1 || !s.pushed[0].Modified.Equal(clock) {
+		t.Fatalf("expected the bucket to be modified at %v: got %v", clock, s.pushed)
+	}
+
+	// ensure the export's creation time is from the clock.
+	data, err := db.(*DB).ExportBuckets([]byte("bucket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := VerifyExport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.Created.Equal(clock) {
+		t.Fatalf("expected the export to be created at %v: got %v", clock, info.Created)
+	}
+}
+
+func TestMaxBuckets(t *testing.T) {
+	db, err := walletdb.Create("localdb", "maxbuckets.db", WithMaxBuckets(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, nm := range []string{"a", "b", "a"} {
+			_, err := tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure a bucket beyond the limit is rejected.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		_, err := tx.CreateTopLevelBucket([]byte("c"))
+		return err
+	})
+	if !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("expected %v: got %v", ErrTooManyBuckets, err)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/linden/tempdb"
+)
+
+// a decoded record doesn't encode back to the stored bytes, see `WithParanoidChecks`.
+var ErrEncodingMismatch = errors.New("record does not re-encode to its stored bytes")
+
+// re-encode every record decoded on open and compare it to the stored bytes, failing with `ErrEncodingMismatch` if they
+// differ, to catch encoder and decoder bugs. it relies on equal buckets encoding to the same bytes, so it's meant for
+// development with every record written by this version in the same configuration, records written with another
+// codec, compression, or by an older version fail the check.
+func WithParanoidChecks() Option {
+	return func(cfg *config) {
+		cfg.paranoid = true
+	}
+}
+
+// check a decoded record re-encodes to its stored bytes, which are plaintext if plain is set.
+func (cfg *config) recheck(key tempdb.BucketID, v []byte, plain bool, bkt *tempdb.Bucket) error {
+	if !plain {
+		var err error
+
+		v, err = cfg.decryptRecord(v, bucketStore, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	enc, err := cfg.marshal(bkt)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(enc, v) {
+		return fmt.Errorf("%w: record %d", ErrEncodingMismatch, key)
+	}
+
+	return nil
+}
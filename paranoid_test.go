@@ -0,0 +1,56 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+// a codec whose decoder adds a key the encoder never wrote.
+type asymmetricCodec struct{}
+
+func (asymmetricCodec) Encode(bkt *tempdb.Bucket) ([]byte, error) {
+	return encode(bkt)
+}
+
+func (asymmetricCodec) Decode(v []byte) (tempdb.Bucket, error) {
+	bkt, err := decode(v)
+	if err == nil && bkt.Parent != tempdb.RootBucketID {
+		bkt.Value["extra"] = []byte("value")
+	}
+
+	return bkt, err
+}
+
+func TestParanoidChecks(t *testing.T) {
+	nm := "paranoid.db"
+
+	db := populated(t, nm)
+	db.Close()
+
+	// ensure a symmetric codec passes.
+	db, err := walletdb.Open("localdb", nm, WithParanoidChecks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure the asymmetry is detected.
+	_, err = walletdb.Open("localdb", nm, WithCodec(asymmetricCodec{}), WithParanoidChecks())
+	if !errors.Is(err, ErrEncodingMismatch) {
+		t.Fatalf("expected %v: got %v", ErrEncodingMismatch, err)
+	}
+
+	// ensure it's only detected under paranoid checks.
+	db, err = walletdb.Open("localdb", nm, WithCodec(asymmetricCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+}
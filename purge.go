@@ -0,0 +1,58 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// stop iterating once a bucket is known not to be empty.
+var errNotEmpty = errors.New("bucket is not empty")
+
+// delete every top-level bucket without keys or nested buckets, returning the names of the removed buckets.
+// the buckets are deleted in a single transaction, so they're removed from storage in a single write.
+func (db *DB) PurgeEmptyBuckets() (removed [][]byte, err error) {
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		// reset the names, in case the transaction is retried.
+		removed = nil
+
+		err := tx.ForEachBucket(func(key []byte) error {
+			// nested buckets are iterated as keys, so an empty iteration means the bucket is empty.
+			err := tx.ReadBucket(key).ForEach(func(k, v []byte) error {
+				return errNotEmpty
+			})
+
+			if errors.Is(err, errNotEmpty) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			removed = append(removed, append([]byte(nil), key...))
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// delete the buckets once they're iterated.
+		for _, nm := range removed {
+			err = tx.DeleteTopLevelBucket(nm)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,82 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestPurgeEmptyBuckets(t *testing.T) {
+	// the name of the database.
+	nm := "purge.db"
+
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, k := range []string{"empty-a", "empty-b", "keys", "nested"} {
+			_, err := tx.CreateTopLevelBucket([]byte(k))
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.ReadWriteBucket([]byte("keys")).Put([]byte("key"), []byte("value"))
+		if err != nil {
+			return err
+		}
+
+		// a bucket holding only an empty nested bucket isn't empty.
+		_, err = tx.ReadWriteBucket([]byte("nested")).CreateBucket([]byte("child"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := db.(*DB).PurgeEmptyBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.SortFunc(removed, bytes.Compare)
+
+	if len(removed) != 2 || string(removed[0]) != "empty-a" || string(removed[1]) != "empty-b" {
+		t.Fatalf("expected the empty buckets to be removed: got %q", removed)
+	}
+
+	// ensure the buckets are removed from storage.
+	db.Close()
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		for _, k := range []string{"empty-a", "empty-b"} {
+			if tx.ReadBucket([]byte(k)) != nil {
+				t.Fatalf("expected %s to be removed", k)
+			}
+		}
+
+		for _, k := range []string{"keys", "nested"} {
+			if tx.ReadBucket([]byte(k)) == nil {
+				t.Fatalf("expected %s to be kept", k)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// the least quota a new database needs, privacy modes can report a quota of 0 or close to it.
+const minQuota = 1 << 20
+
+var (
+	// the browser gives the origin no storage, fall back to an in-memory database such as tempdb.
+	ErrNoStorage = errors.New("no storage is available")
+
+	// the browser does not support estimating storage.
+	ErrEstimateUnsupported = errors.New("estimating storage is not supported")
+
+	// the browser does not support persistent storage.
+	ErrPersistUnsupported = errors.New("persistent storage is not supported")
+)
+
+// estimate the origin's storage, it's a variable so it can be replaced in tests.
+var estimate = func() (js.Value, error) {
+	storage := js.Global().Get("navigator").Get("storage")
+
+	// ensure the browser supports estimating storage.
+	if storage.IsUndefined() || storage.Get("estimate").IsUndefined() {
+		return js.Value{}, ErrEstimateUnsupported
+	}
+
+	return await(storage.Call("estimate"))
+}
+
+// get the number of bytes the origin is using and its quota, as estimated by the browser.
+func Usage() (usage uint64, quota uint64, err error) {
+	est, err := estimate()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(est.Get("usage").Float()), uint64(est.Get("quota").Float()), nil
+}
+
+// report whether the origin's storage is persistent, so the browser won't evict the databases under storage pressure.
+// if request is true and it isn't, persistence is requested first, browsers may ask the user or decide themselves.
+// best-effort storage can be evicted, so apps may want to warn the user their data could be lost.
+func Persisted(request bool) (bool, error) {
+	storage := js.Global().Get("navigator").Get("storage")
+
+	// ensure the browser supports persistent storage.
+	if storage.IsUndefined() || storage.Get("persisted").IsUndefined() {
+		return false, ErrPersistUnsupported
+	}
+
+	v, err := await(storage.Call("persisted"))
+	if err != nil {
+		return false, err
+	}
+
+	if v.Bool() || !request || storage.Get("persist").IsUndefined() {
+		return v.Bool(), nil
+	}
+
+	v, err = await(storage.Call("persist"))
+	if err != nil {
+		return false, err
+	}
+
+	return v.Bool(), nil
+}
+
+// ensure the origin has storage, so creating a database fails early instead of on the first commit.
+func checkQuota() error {
+	_, quota, err := Usage()
+
+	// skip the check when the browser can't estimate storage.
+	if errors.Is(err, ErrEstimateUnsupported) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if quota < minQuota {
+		return ErrNoStorage
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"syscall/js"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestNoStorage(t *testing.T) {
+	// mock a browser with no quota.
+	orig := estimate
+	defer func() { estimate = orig }()
+
+	estimate = func() (js.Value, error) {
+		return js.ValueOf(map[string]any{"usage": 0, "quota": 0}), nil
+	}
+
+	_, err := walletdb.Create("localdb", "quota.db")
+	if !errors.Is(err, ErrNoStorage) {
+		t.Fatalf("expected %v: got %v", ErrNoStorage, err)
+	}
+
+	// ensure the database was not created.
+	estimate = orig
+
+	_, err = walletdb.Open("localdb", "quota.db")
+	if !errors.Is(err, walletdb.ErrDbDoesNotExist) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbDoesNotExist, err)
+	}
+}
+
+func TestPersisted(t *testing.T) {
+	persisted, err := Persisted(false)
+	if errors.Is(err, ErrPersistUnsupported) {
+		t.Skip(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure requesting persistence doesn't lose it.
+	requested, err := Persisted(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if persisted && !requested {
+		t.Fatal("expected the storage to stay persistent")
+	}
+}
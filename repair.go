@@ -0,0 +1,219 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+var (
+	// the bucket's parent is not stored.
+	ErrOrphan = errors.New("parent bucket does not exist")
+
+	// the bucket is also stored under another key.
+	ErrDuplicate = errors.New("bucket is stored more than once")
+)
+
+// a record dropped by `Repair`.
+type Dropped struct {
+	// the key the record was stored under.
+	Key tempdb.BucketID
+
+	// why the record was dropped.
+	Reason error
+}
+
+// salvage a damaged database, keeping every bucket that can be decoded and dropping the rest.
+// records that can't be decoded, duplicates and buckets whose parent is missing are dropped.
+// the buckets are rewritten under their ID along with the bucket count, so the database can be opened.
+// this is a best-effort operation, the database should not be open while it runs.
+func Repair(name string, opts ...Option) ([]Dropped, error) {
+	cfg := newConfig(opts...)
+
+	b, exist, err := cfg.backend(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer b.Close()
+
+	if !exist {
+		return nil, walletdb.ErrDbDoesNotExist
+	}
+
+	// get every stored record.
+	recs, err := cfg.load(b)
+	if err != nil {
+		return nil, err
+	}
+
+	// ensure we have the key, so buckets aren't dropped because they can't be decrypted.
+	_, err = cfg.encryption(recs.Meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// finish a flush that was interrupted, so its buckets aren't dropped.
+	err = replay(b, recs)
+	if err != nil {
+		return nil, err
+	}
+
+	// find the records stored in plaintext.
+	plain, err := parseKeys(recs.Meta[plaintextKey])
+	if err != nil {
+		return nil, err
+	}
+
+	// sort the keys, so the buckets are repaired in a stable order.
+	keys := make([]tempdb.BucketID, 0, len(recs.Buckets))
+
+	for key := range recs.Buckets {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	var dropped []Dropped
+
+	// decode every bucket.
+	bkts := make(map[tempdb.BucketID]tempdb.Bucket)
+
+	for _, key := range keys {
+		bkt, err := cfg.decodeRecord(key, recs.Buckets[key], plain)
+		if err != nil {
+			dropped = append(dropped, Dropped{Key: key, Reason: err})
+			continue
+		}
+
+		bkts[key] = bkt
+	}
+
+	// the buckets to keep, by ID.
+	keep := make(map[tempdb.BucketID]tempdb.Bucket)
+
+	// keep buckets stored under their ID first, so they win over duplicates.
+	for _, key := range keys {
+		if bkt, ok := bkts[key]; ok && bkt.ID == key {
+			keep[bkt.ID] = bkt
+		}
+	}
+
+	for _, key := range keys {
+		bkt, ok := bkts[key]
+		if !ok || bkt.ID == key {
+			continue
+		}
+
+		if _, ok := keep[bkt.ID]; ok {
+			dropped = append(dropped, Dropped{Key: key, Reason: ErrDuplicate})
+			continue
+		}
+
+		keep[bkt.ID] = bkt
+	}
+
+	// drop buckets whose parent is missing, until every parent exists.
+	for {
+		// the key each bucket was stored under.
+		var orphans []tempdb.BucketID
+
+		for id, bkt := range keep {
+			parent := bkt.Parent
+
+			// shards belong to their bucket.
+			if b, ok := shardOf(id); ok {
+				parent = b
+			}
+
+			if parent == tempdb.RootBucketID {
+				continue
+			}
+
+			if _, ok := keep[parent]; !ok {
+				orphans = append(orphans, id)
+			}
+		}
+
+		if len(orphans) == 0 {
+			break
+		}
+
+		for _, id := range orphans {
+			dropped = append(dropped, Dropped{Key: stored(bkts, id), Reason: ErrOrphan})
+			delete(keep, id)
+		}
+	}
+
+	ch := &Changes{
+		Records: Records{
+			Buckets: make(map[tempdb.BucketID][]byte),
+			Meta:    make(map[string][]byte),
+		},
+
+		// delete every record, the kept buckets are put back under their ID.
+		Deletes: keys,
+	}
+
+	// find the top-level bucket of every kept bucket, so only the configured buckets are encrypted.
+	var kept []tempdb.Bucket
+
+	for _, bkt := range keep {
+		kept = append(kept, bkt)
+	}
+
+	rts := roots(kept)
+
+	// the kept records stored in plaintext.
+	plain = make(map[tempdb.BucketID]bool)
+
+	for id, bkt := range keep {
+		v, err := cfg.marshal(&bkt)
+		if err != nil {
+			return nil, err
+		}
+
+		v, plain[id], err = cfg.encryptIn(v, rts[id], recordData(bucketStore, id))
+		if err != nil {
+			return nil, err
+		}
+
+		ch.Buckets[id] = v
+	}
+
+	ch.Meta[countKey] = []byte(strconv.Itoa(len(ch.Buckets)))
+	ch.Meta[plaintextKey] = formatKeys(plain)
+
+	err = b.Write(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	// sort the dropped records by key.
+	slices.SortFunc(dropped, func(a, b Dropped) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+
+	return dropped, nil
+}
+
+// find the key a bucket was stored under.
+func stored(bkts map[tempdb.BucketID]tempdb.Bucket, id tempdb.BucketID) tempdb.BucketID {
+	if bkt, ok := bkts[id]; ok && bkt.ID == id {
+		return id
+	}
+
+	for key, bkt := range bkts {
+		if bkt.ID == id {
+			return key
+		}
+	}
+
+	return id
+}
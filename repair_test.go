@@ -0,0 +1,120 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+func TestRepair(t *testing.T) {
+	// the name of the database.
+	nm := "repair.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("parent"))
+		if err != nil {
+			return err
+		}
+
+		nbkt, err := bkt.CreateBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+
+		return nbkt.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// open a second connection to damage the database.
+	idb, err := indexeddb.New(nm, version<<16, func(up *indexeddb.Upgrade) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itx, err := idb.NewTransaction([]string{bucketStore}, indexeddb.ReadWriteMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	str := itx.Store(bucketStore)
+
+	// store a record that can't be decoded.
+	err = str.Put(100, quote([]byte("garbage")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// store a bucket whose parent doesn't exist.
+	orphan, err := encode(&tempdb.Bucket{ID: 101, Parent: 99, Key: []byte("orphan")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = str.Put(101, quote(orphan))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the damaged database can't be opened.
+	_, err = walletdb.Open("localdb", nm)
+	if err == nil {
+		t.Fatal("expected the damaged database to fail to open")
+	}
+
+	dropped, err := Repair(nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dropped) != 2 {
+		t.Fatalf("expected 2 dropped records: got %v", dropped)
+	}
+
+	if dropped[0].Key != 100 {
+		t.Fatalf("expected the undecodable record to be dropped: got %v", dropped[0])
+	}
+
+	if dropped[1].Key != 101 || !errors.Is(dropped[1].Reason, ErrOrphan) {
+		t.Fatalf("expected the orphan to be dropped: got %v", dropped[1])
+	}
+
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("parent"))
+		if bkt == nil {
+			t.Fatal("expected the parent bucket to exist")
+		}
+
+		nbkt := bkt.NestedReadBucket([]byte("child"))
+		if nbkt == nil {
+			t.Fatal("expected the child bucket to exist")
+		}
+
+		if v := nbkt.Get([]byte("key")); !bytes.Equal(v, []byte("value")) {
+			t.Fatalf("expected value but got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// the metadata key for the data schema version.
+	schemaKey = "schema"
+
+	// the version of the data schema, increased when stored buckets gain data older versions would drop.
+	// databases from before the version was stored are version 1.
+	schemaVersion = 1
+)
+
+// the database was written by a newer version of localdb, which older versions may not decode without losing data.
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+// open databases with a newer data schema than this version of localdb supports, see `ErrSchemaTooNew`.
+// fields the newer schema added may be dropped when the buckets are written again.
+func WithForceSchema() Option {
+	return func(cfg *config) {
+		cfg.forceSchema = true
+	}
+}
+
+// ensure the stored data schema is supported.
+func (cfg *config) checkSchema(meta map[string][]byte) error {
+	v, ok := meta[schemaKey]
+	if !ok || cfg.forceSchema {
+		return nil
+	}
+
+	schema, err := strconv.Atoi(string(v))
+	if err != nil {
+		return err
+	}
+
+	if schema > schemaVersion {
+		return fmt.Errorf("%w: %d, supports up to %d", ErrSchemaTooNew, schema, schemaVersion)
+	}
+
+	return nil
+}
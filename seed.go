@@ -0,0 +1,33 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// the database already has buckets.
+var ErrDatabaseNotEmpty = errors.New("database is not empty")
+
+// populate an empty database from an export, such as a default layout embedded in the app for demos and tests.
+// it's `ImportBuckets` for a fresh database, every bucket in the export is written in a single transaction. the export
+// is validated before anything is written, a database with any buckets fails with `ErrDatabaseNotEmpty`.
+func (db *DB) SeedFrom(data []byte) error {
+	children, err := readChildren(data, db.cfg)
+	if err != nil {
+		return err
+	}
+
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ttx := tx.(*Transaction)
+
+		// check in the transaction, so nothing is created in between.
+		if len(ttx.State.Buckets) != 0 {
+			return ErrDatabaseNotEmpty
+		}
+
+		return importInto(ttx, children, MergeOverwrite)
+	})
+}
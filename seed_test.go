@@ -0,0 +1,64 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestSeedFrom(t *testing.T) {
+	src := populated(t, "seed-source.db")
+	defer src.Close()
+
+	data, err := src.(*DB).Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := walletdb.Create("localdb", "seed.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = db.(*DB).SeedFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the parent and its child were seeded.
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("parent"))
+		if bkt == nil {
+			t.Fatal("expected the parent bucket")
+		}
+
+		if v := bkt.Get([]byte("a")); string(v) != "1" {
+			t.Fatalf("expected 1: got %q", v)
+		}
+
+		nbkt := bkt.NestedReadBucket([]byte("child"))
+		if nbkt == nil {
+			t.Fatal("expected the child bucket")
+		}
+
+		if v := nbkt.Get([]byte("b")); string(v) != "2" {
+			t.Fatalf("expected 2: got %q", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure a database with buckets isn't seeded again.
+	err = db.(*DB).SeedFrom(data)
+	if !errors.Is(err, ErrDatabaseNotEmpty) {
+		t.Fatalf("expected %v: got %v", ErrDatabaseNotEmpty, err)
+	}
+}
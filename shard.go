@@ -0,0 +1,153 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// the bit the shard number starts at in a shard's record key, bucket IDs must be below it.
+// the keys stay below 2^53, so they're exact as javascript numbers.
+const shardShift = 44
+
+// split buckets with more than keys keys into records by the first byte of their keys, so a change only rewrites the
+// records of the keys it changed instead of the whole bucket. the bucket's own record holds its empty key, if it has
+// one. sharded buckets are reassembled on open with or without the option.
+func WithSharding(keys int) Option {
+	return func(cfg *config) {
+		cfg.shardKeys = keys
+	}
+}
+
+// get the record key of a bucket's shard, holding the keys starting with the byte.
+func shardKey(id tempdb.BucketID, b byte) tempdb.BucketID {
+	return id | (tempdb.BucketID(b)+1)<<shardShift
+}
+
+// get the ID of the bucket a shard belongs to, if the record key is a shard's.
+func shardOf(key tempdb.BucketID) (tempdb.BucketID, bool) {
+	return key & (1<<shardShift - 1), key>>shardShift != 0
+}
+
+// get the ID of the bucket a record belongs to.
+func owner(key tempdb.BucketID) tempdb.BucketID {
+	id, _ := shardOf(key)
+	return id
+}
+
+// split a bucket into its records, a shard is stored as a bucket with the shard's record key as its ID.
+func (cfg *config) split(bkt tempdb.Bucket) []tempdb.Bucket {
+	if cfg.shardKeys <= 0 || len(bkt.Value) <= cfg.shardKeys {
+		return []tempdb.Bucket{bkt}
+	}
+
+	base := bkt
+	base.Value = make(map[string][]byte)
+
+	// the shards, by the first byte of their keys.
+	shards := make(map[byte]*tempdb.Bucket)
+
+	for k, v := range bkt.Value {
+		if k == "" {
+			base.Value[k] = v
+			continue
+		}
+
+		s, ok := shards[k[0]]
+		if !ok {
+			s = &tempdb.Bucket{
+				ID:     shardKey(bkt.ID, k[0]),
+				Parent: bkt.Parent,
+				Key:    bkt.Key,
+				Value:  make(map[string][]byte),
+			}
+
+			shards[k[0]] = s
+		}
+
+		s.Value[k] = v
+	}
+
+	recs := []tempdb.Bucket{base}
+
+	for _, s := range shards {
+		recs = append(recs, *s)
+	}
+
+	// sort the shards, so they're written in a stable order.
+	slices.SortFunc(recs[1:], func(a, b tempdb.Bucket) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return recs
+}
+
+// split the buckets into their records, deleting the shards sharded and deleted buckets no longer have.
+// the lock must be held.
+func (db *DB) shard(puts []tempdb.Bucket, dels []tempdb.BucketID) ([]tempdb.Bucket, []tempdb.BucketID) {
+	// the stored shards of every bucket.
+	stored := make(map[tempdb.BucketID][]tempdb.BucketID)
+
+	for key := range db.records {
+		if id, ok := shardOf(key); ok {
+			stored[id] = append(stored[id], key)
+		}
+	}
+
+	if db.cfg.shardKeys <= 0 && len(stored) == 0 {
+		return puts, dels
+	}
+
+	var rputs []tempdb.Bucket
+
+	rdels := slices.Clone(dels)
+
+	for _, id := range dels {
+		rdels = append(rdels, stored[id]...)
+	}
+
+	for _, bkt := range puts {
+		recs := db.cfg.split(bkt)
+		rputs = append(rputs, recs...)
+
+		for _, key := range stored[bkt.ID] {
+			if !slices.ContainsFunc(recs, func(r tempdb.Bucket) bool { return r.ID == key }) {
+				rdels = append(rdels, key)
+			}
+		}
+	}
+
+	// a shard can be deleted by both, such as when flushing a deleted bucket.
+	slices.Sort(rdels)
+
+	return rputs, slices.Compact(rdels)
+}
+
+// merge the decoded shards into their buckets, returning the record keys of the shards.
+func unshard(bkts map[tempdb.BucketID]tempdb.Bucket) ([]tempdb.BucketID, error) {
+	var shards []tempdb.BucketID
+
+	for key, s := range bkts {
+		id, ok := shardOf(key)
+		if !ok || s.ID != key {
+			continue
+		}
+
+		bkt, ok := bkts[id]
+		if !ok {
+			return nil, fmt.Errorf("record %d: %w", key, ErrOrphan)
+		}
+
+		for k, v := range s.Value {
+			bkt.Value[k] = v
+		}
+
+		shards = append(shards, key)
+	}
+
+	return shards, nil
+}
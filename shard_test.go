@@ -0,0 +1,131 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestSharding(t *testing.T) {
+	// the name of the database.
+	nm := "shard.db"
+
+	db, err := walletdb.Create("localdb", nm, WithSharding(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// put 260 keys, starting with 26 different bytes.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("large"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 260; i++ {
+			err = bkt.Put([]byte(fmt.Sprintf("%c%d", 'a'+i%26, i)), []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bucket's record and 26 shards.
+	if n := len(before.Buckets); n != 27 {
+		t.Fatalf("expected 27 records: got %d", n)
+	}
+
+	// update a single key.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.ReadWriteBucket([]byte("large")).Put([]byte("c2"), []byte("changed"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure only the shard holding the key was rewritten.
+	var rewritten int
+
+	for key, v := range after.Buckets {
+		if !bytes.Equal(before.Buckets[key], v) {
+			rewritten++
+		}
+	}
+
+	if rewritten != 1 {
+		t.Fatalf("expected 1 record to be rewritten: got %d", rewritten)
+	}
+
+	db.Close()
+
+	// ensure the shards are reassembled, without the option.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("large"))
+
+		var n int
+
+		err := bkt.ForEach(func(k, v []byte) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if n != 260 {
+			t.Fatalf("expected 260 keys: got %d", n)
+		}
+
+		if v := string(bkt.Get([]byte("c2"))); v != "changed" {
+			t.Fatalf("expected changed: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the shards are deleted with the bucket.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket([]byte("large"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := db.(*DB).backend.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(recs.Buckets); n != 0 {
+		t.Fatalf("expected every record to be deleted: got %d", n)
+	}
+}
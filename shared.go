@@ -0,0 +1,256 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"slices"
+	"strings"
+	"syscall/js"
+
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+// the separator between a database's name and its store names, in a shared indexeddb database.
+const sharedSeparator = "/"
+
+// localdb's stores, every database has its own.
+var stores = []string{bucketStore, metaStore, indexStore, backupStore, keyStore, stagingStore}
+
+// Shared stores the database in the container indexeddb database alongside other localdb databases, each database's
+// stores are prefixed with its name, such as "wallet/buckets". the stores are created on the first write, which
+// upgrades the container, so the other databases close their connection and reopen it on their next read or write.
+// auto-backups and raw transactions aren't supported, use `DropShared` to delete a database.
+func Shared(container string) BackendFunc {
+	return func(name string) (Backend, bool, error) {
+		b := &sharedBackend{
+			container: container,
+			prefix:    name + sharedSeparator,
+		}
+
+		err := b.connect(false)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return b, !b.missing, nil
+	}
+}
+
+// Ensure `sharedBackend` complies with the `Backend` interface.
+var _ Backend = (*sharedBackend)(nil)
+
+// an indexeddb backend in a shared indexeddb database.
+type sharedBackend struct {
+	container string
+	prefix    string
+
+	// the connection, nil once it's closed for another database to upgrade the container.
+	b *idbBackend
+
+	// whether any of the database's stores are missing, they're created on the first write.
+	missing bool
+
+	// closes the connection when the container is upgraded.
+	change js.Func
+
+	// counts the requests, see `WithRoundTrips`.
+	trips *RoundTrips
+}
+
+func (b *sharedBackend) countTrips(t *RoundTrips) {
+	b.trips = t
+
+	if b.b != nil {
+		b.b.trips = t
+	}
+}
+
+func (b *sharedBackend) Load() (*Records, error) {
+	ib, err := b.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing is stored until the first write.
+	if b.missing {
+		return &Records{
+			Buckets: make(map[tempdb.BucketID][]byte),
+			Meta:    make(map[string][]byte),
+		}, nil
+	}
+
+	return ib.Load()
+}
+
+func (b *sharedBackend) Write(ch *Changes) error {
+	// create the stores.
+	if b.missing {
+		err := b.connect(true)
+		if err != nil {
+			return err
+		}
+	}
+
+	ib, err := b.conn()
+	if err != nil {
+		return err
+	}
+
+	return ib.Write(ch)
+}
+
+func (b *sharedBackend) Close() error {
+	b.release()
+
+	if b.b == nil {
+		return nil
+	}
+
+	err := b.b.Close()
+	b.b = nil
+
+	return err
+}
+
+// get the connection, reopening it if it was closed for an upgrade.
+func (b *sharedBackend) conn() (*idbBackend, error) {
+	if b.b == nil {
+		err := b.connect(false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b.b, nil
+}
+
+// open the container, creating the database's stores if any are missing and create is set.
+func (b *sharedBackend) connect(create bool) error {
+	// close the current connection, so it doesn't block the upgrade.
+	if b.b != nil {
+		b.b.Close()
+		b.b = nil
+	}
+
+	ver, names, err := inspect(b.container)
+	if err != nil {
+		return err
+	}
+
+	b.missing = slices.ContainsFunc(stores, func(str string) bool {
+		return !slices.Contains(names, b.prefix+str)
+	})
+
+	// upgrade the container to create the stores.
+	if create && b.missing {
+		ver++
+		b.missing = false
+	}
+
+	idb, err := indexeddb.New(b.container, ver, func(up *indexeddb.Upgrade) error {
+		for _, str := range stores {
+			createStore(up, b.prefix+str)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// close the connection when another database upgrades the container, the upgrade is blocked until it's closed.
+	b.release()
+
+	b.change = js.FuncOf(func(this js.Value, args []js.Value) any {
+		if b.b != nil {
+			b.b.Close()
+			b.b = nil
+		}
+
+		return nil
+	})
+
+	value(idb).Set("onversionchange", b.change)
+
+	b.b = &idbBackend{
+		idb:    idb,
+		prefix: b.prefix,
+		trips:  b.trips,
+	}
+
+	return nil
+}
+
+// release the function handling upgrades, if there is one.
+func (b *sharedBackend) release() {
+	if !b.change.IsUndefined() {
+		b.change.Release()
+		b.change = js.Func{}
+	}
+}
+
+// list the databases stored in the container.
+func ListShared(container string) ([]string, error) {
+	_, names, err := inspect(container)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbs []string
+
+	// databases are recognised by their buckets store.
+	for _, nm := range names {
+		if db, ok := strings.CutSuffix(nm, sharedSeparator+bucketStore); ok {
+			dbs = append(dbs, db)
+		}
+	}
+
+	return dbs, nil
+}
+
+// delete a database from the container, leaving the other databases alone.
+// the database's connections are closed, it must not be used afterwards.
+func DropShared(container, name string) error {
+	ver, names, err := inspect(container)
+	if err != nil {
+		return err
+	}
+
+	prefix := name + sharedSeparator
+
+	idb, err := indexeddb.New(container, ver+1, func(up *indexeddb.Upgrade) error {
+		for _, str := range stores {
+			if slices.Contains(names, prefix+str) {
+				value(up).Call("deleteObjectStore", prefix+str)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return idb.Close()
+}
+
+// get the version and store names of an indexeddb database, without upgrading it.
+func inspect(name string) (int, []string, error) {
+	// open the database without a version, so it isn't upgraded.
+	idb, err := request(indexeddb.IndexedDB.Call("open", name))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer idb.Call("close")
+
+	strs := idb.Get("objectStoreNames")
+	names := make([]string, strs.Length())
+
+	for i := range names {
+		names[i] = strs.Index(i).String()
+	}
+
+	return idb.Get("version").Int(), names, nil
+}
@@ -0,0 +1,115 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestShared(t *testing.T) {
+	// the indexeddb database shared by the databases.
+	container := "shared"
+
+	// create 2 databases in the container, with the same bucket.
+	for _, nm := range []string{"alice", "bob"} {
+		db, err := walletdb.Create("localdb", nm, WithBackend(Shared(container)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt, err := tx.CreateTopLevelBucket([]byte("wallet"))
+			if err != nil {
+				return err
+			}
+
+			return bkt.Put([]byte("owner"), []byte(nm))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// keep alice open, so creating bob has to close her connection to upgrade the container.
+		if nm == "alice" {
+			defer db.Close()
+		} else {
+			db.Close()
+		}
+	}
+
+	lst, err := ListShared(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(lst)
+
+	if !slices.Equal(lst, []string{"alice", "bob"}) {
+		t.Fatalf("expected alice and bob: got %v", lst)
+	}
+
+	// ensure each database only sees its own buckets.
+	owner := func(nm string) string {
+		db, err := walletdb.Open("localdb", nm, WithBackend(Shared(container)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer db.Close()
+
+		var v []byte
+
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			v = tx.ReadBucket([]byte("wallet")).Get([]byte("owner"))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(v)
+	}
+
+	for _, nm := range []string{"alice", "bob"} {
+		if v := owner(nm); v != nm {
+			t.Fatalf("expected %s: got %s", nm, v)
+		}
+	}
+
+	// drop bob, alice should be left alone.
+	err = DropShared(container, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lst, err = ListShared(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(lst, []string{"alice"}) {
+		t.Fatalf("expected only alice: got %v", lst)
+	}
+
+	_, err = walletdb.Open("localdb", "bob", WithBackend(Shared(container)))
+	if err != walletdb.ErrDbDoesNotExist {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbDoesNotExist, err)
+	}
+
+	// ensure opening bob didn't create it again.
+	lst, err = ListShared(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(lst, []string{"alice"}) {
+		t.Fatalf("expected only alice: got %v", lst)
+	}
+
+	if v := owner("alice"); v != "alice" {
+		t.Fatalf("expected alice: got %s", v)
+	}
+}
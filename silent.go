@@ -0,0 +1,24 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/linden/tempdb"
+)
+
+// a handler which is never enabled, so messages are dropped before they're formatted.
+type silentHandler struct{}
+
+func (silentHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (silentHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h silentHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h silentHandler) WithGroup(string) slog.Handler           { return h }
+
+// disable logging entirely, such as in production builds. the logger is shared with tempdb by every database in the
+// page, so they're all silenced. it replaces the logger, setting `tempdb.Logger` again enables logging.
+func SetSilent() {
+	tempdb.Logger = slog.New(silentHandler{})
+}
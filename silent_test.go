@@ -0,0 +1,67 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+func TestSetSilent(t *testing.T) {
+	// restore the test logger.
+	prev := tempdb.Logger
+	defer func() { tempdb.Logger = prev }()
+
+	buf := new(bytes.Buffer)
+
+	update := func(nm string) {
+		db, err := walletdb.Create("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			_, err := tx.CreateTopLevelBucket([]byte("bucket"))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure transactions log.
+	tempdb.Logger = slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+
+	update("logged.db")
+
+	if buf.Len() == 0 {
+		t.Fatal("expected the transaction to be logged")
+	}
+
+	SetSilent()
+
+	buf.Reset()
+
+	update("silent.db")
+
+	// ensure nothing was logged, through tempdb or localdb.
+	Logger().Error("error")
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing to be logged: got %q", buf)
+	}
+
+	// ensure nothing is logged at any level.
+	for _, lvl := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		if Logger().Enabled(context.Background(), lvl) {
+			t.Fatalf("expected %s to be disabled", lvl)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// how a bucket is stored, see `BucketStorageInfo`.
+type BucketStorage struct {
+	// the size of the bucket's stored records, as written by the codec, compression and encryption.
+	EncodedBytes int
+
+	// whether any of the bucket's records is compressed, see `WithCompression`.
+	Compressed bool
+
+	// whether the bucket's records are encrypted, see `WithEncryptedBuckets`.
+	Encrypted bool
+}
+
+// report how a top-level bucket is stored, such as to tune `WithMinCompressSize` or `WithEncryptedBuckets`. the stored
+// records are read like `Verify`, so buckets which haven't been flushed yet aren't found. a sharded bucket is reported
+// as a whole, the bucket's nested buckets have records of their own and aren't included.
+func (db *DB) BucketStorageInfo(name []byte) (BucketStorage, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	id, ok := topLevel(db.State.Buckets, name)
+	if !ok {
+		return BucketStorage{}, walletdb.ErrBucketNotFound
+	}
+
+	recs, err := db.cfg.load(db.backend)
+	if err != nil {
+		return BucketStorage{}, err
+	}
+
+	plain, err := parseKeys(recs.Meta[plaintextKey])
+	if err != nil {
+		return BucketStorage{}, err
+	}
+
+	var info BucketStorage
+
+	found := false
+
+	for key, v := range recs.Buckets {
+		if owner(key) != id {
+			continue
+		}
+
+		found = true
+
+		info.EncodedBytes += len(v)
+		info.Encrypted = db.cfg.aead != nil && !plain[key]
+
+		// the compression prefix is under the encryption.
+		if info.Encrypted {
+			v, err = db.cfg.decryptRecord(v, bucketStore, key)
+			if err != nil {
+				return BucketStorage{}, fmt.Errorf("record %d: %w", key, err)
+			}
+		}
+
+		if bytes.HasPrefix(v, []byte(compressedPrefix)) {
+			info.Compressed = true
+		}
+	}
+
+	if !found {
+		return BucketStorage{}, fmt.Errorf("%w: not stored", walletdb.ErrBucketNotFound)
+	}
+
+	return info, nil
+}
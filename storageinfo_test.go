@@ -0,0 +1,75 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestBucketStorageInfo(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	// encrypt every bucket but "plain".
+	encrypted := func(name []byte) bool {
+		return string(name) != "plain"
+	}
+
+	db, err := walletdb.Create("localdb", "storageinfo.db", WithCompression(), WithEncryptionKey(key), WithEncryptedBuckets(encrypted))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, nm := range []string{"secret", "plain"} {
+			bkt, err := tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put([]byte("key"), bytes.Repeat([]byte("value"), 100))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := db.(*DB).BucketStorageInfo([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.Compressed || !info.Encrypted || info.EncodedBytes == 0 {
+		t.Fatalf("expected a compressed, encrypted bucket: got %+v", info)
+	}
+
+	// ensure the size is of the stored record, not the value.
+	if info.EncodedBytes >= 500 {
+		t.Fatalf("expected fewer than 500 bytes: got %d", info.EncodedBytes)
+	}
+
+	info, err = db.(*DB).BucketStorageInfo([]byte("plain"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.Compressed || info.Encrypted {
+		t.Fatalf("expected a compressed, plaintext bucket: got %+v", info)
+	}
+
+	// ensure a missing bucket is reported.
+	_, err = db.(*DB).BucketStorageInfo([]byte("missing"))
+	if !errors.Is(err, walletdb.ErrBucketNotFound) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrBucketNotFound, err)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+	"unicode/utf8"
+
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+// the metadata key marking the bucket records as stored without quoting, see `WithStrictUTF8`.
+const rawKey = "raw"
+
+// a record isn't valid UTF-8, so it can't be stored in strict UTF-8 mode.
+var ErrNotUTF8 = errors.New("record is not valid UTF-8")
+
+// store the bucket records in indexeddb as-is instead of quoting them, rejecting records that aren't valid UTF-8.
+// this saves space and time for databases only holding UTF-8, such as with the `JSON` codec, but gob and encrypted
+// records are binary so they can't be stored. the mode is chosen when the database is created and stored with it,
+// so it's ignored when opening an existing database.
+func WithStrictUTF8() Option {
+	return func(cfg *config) {
+		cfg.strictUTF8 = true
+	}
+}
+
+// check if the bucket records are stored without quoting, given the metadata.
+func isRaw(meta map[string][]byte) bool {
+	return string(meta[rawKey]) == "1"
+}
+
+// check if the bucket records are stored without quoting, from the changes or the stored metadata.
+func (b *idbBackend) raw(meta *indexeddb.Store, ch *Changes) (bool, error) {
+	if v, ok := ch.Meta[rawKey]; ok {
+		return string(v) == "1", nil
+	}
+
+	// nothing else reads the mode, so skip the request.
+	if len(ch.Buckets) == 0 {
+		return false, nil
+	}
+
+	b.trips.get(1)
+
+	v, err := meta.Get(rawKey)
+	if errors.Is(err, indexeddb.ErrValueNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	raw, err := unquote(*v)
+	if err != nil {
+		return false, err
+	}
+
+	return string(raw) == "1", nil
+}
+
+// encode a bucket record to be stored, quoting it unless it's stored as-is.
+func storeValue(id tempdb.BucketID, v []byte, raw bool) (string, error) {
+	if !raw {
+		return quote(v), nil
+	}
+
+	if !utf8.Valid(v) {
+		return "", fmt.Errorf("%w: bucket %d", ErrNotUTF8, id)
+	}
+
+	return string(v), nil
+}
+
+// decode a stored bucket record.
+func loadValue(val js.Value, raw bool) ([]byte, error) {
+	// blobs are stored as-is in either mode, see `WithBlobBuckets`.
+	if isBlob(val) {
+		return readBlob(val)
+	}
+
+	if !raw {
+		return unquote(val)
+	}
+
+	// ensure the value is a string.
+	if t := val.Type(); t != js.TypeString {
+		return nil, fmt.Errorf("expected a type of %s: got %s", js.TypeString, t)
+	}
+
+	return []byte(val.String()), nil
+}
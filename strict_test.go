@@ -0,0 +1,75 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+)
+
+func TestStrictUTF8(t *testing.T) {
+	// the name of the database.
+	nm := "strict.db"
+
+	db, err := walletdb.Create("localdb", nm, WithCodec(JSON), WithStrictUTF8())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "bucket", "välue ✓")
+
+	// ensure the records are stored as-is.
+	itx, err := db.(*DB).RawTransaction([]string{bucketStore}, indexeddb.ReadMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := itx.Store(bucketStore).GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vals) != 1 || !strings.HasPrefix(vals[0].String(), "{") {
+		t.Fatalf("expected an unquoted record: got %v", vals)
+	}
+
+	db.Close()
+
+	// ensure the stored mode is used, without the option.
+	db, err = walletdb.Open("localdb", nm, WithCodec(JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if v := getValue(t, db, "bucket"); v != "välue ✓" {
+		t.Fatalf("expected välue ✓: got %s", v)
+	}
+}
+
+func TestStrictUTF8Invalid(t *testing.T) {
+	// gob records are binary, so they aren't valid UTF-8.
+	db, err := walletdb.Create("localdb", "strict-invalid.db", WithStrictUTF8())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("key"), []byte{0xff, 0xfe})
+	})
+	if !errors.Is(err, ErrNotUTF8) {
+		t.Fatalf("expected %v: got %v", ErrNotUTF8, err)
+	}
+}
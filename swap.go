@@ -0,0 +1,165 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+)
+
+// the database has an open connection in the page.
+var ErrDatabaseOpen = errors.New("database is open")
+
+var (
+	// the open connections to every indexeddb database in the page, by name.
+	connections = make(map[string]int)
+
+	// guards the connections.
+	connectionsLock sync.Mutex
+)
+
+// count a connection to an indexeddb database being opened or closed.
+func connected(name string, n int) {
+	connectionsLock.Lock()
+	defer connectionsLock.Unlock()
+
+	connections[name] += n
+
+	if connections[name] <= 0 {
+		delete(connections, name)
+	}
+}
+
+// swap the contents of 2 indexeddb databases, such as to build a new version of a wallet in b and promote it over a.
+// indexeddb can't rename databases and deleting one is blocked while it has open connections, so the records are
+// swapped in place instead. each database is replaced in a single transaction, if the second fails the first is
+// restored, but a page closed in between leaves both with the same contents.
+// handles opened before the swap would keep the old state and overwrite it, so both databases must be closed, a
+// database with a connection in the page fails with `ErrDatabaseOpen`. the bucket and key indexes are cleared, they're
+// rebuilt as buckets are written again. backups stay with their database.
+func SwapDatabases(a, b string) error {
+	if a == b {
+		return nil
+	}
+
+	// ensure neither database is open in the page.
+	connectionsLock.Lock()
+	for _, nm := range []string{a, b} {
+		if connections[nm] > 0 {
+			connectionsLock.Unlock()
+			return fmt.Errorf("%w: %s", ErrDatabaseOpen, nm)
+		}
+	}
+	connectionsLock.Unlock()
+
+	// ensure both databases exist, opening them would create them.
+	for _, nm := range []string{a, b} {
+		exist, err := idbExists(nm)
+		if err != nil {
+			return err
+		}
+
+		if !exist {
+			return fmt.Errorf("%w: %s", walletdb.ErrDbDoesNotExist, nm)
+		}
+	}
+
+	ab, _, err := IndexedDB(a)
+	if err != nil {
+		return err
+	}
+
+	defer ab.Close()
+
+	bb, _, err := IndexedDB(b)
+	if err != nil {
+		return err
+	}
+
+	defer bb.Close()
+
+	// read both databases before anything is replaced.
+	arecs, err := ab.Load()
+	if err != nil {
+		return err
+	}
+
+	brecs, err := bb.Load()
+	if err != nil {
+		return err
+	}
+
+	err = ab.(*idbBackend).replace(brecs)
+	if err != nil {
+		return err
+	}
+
+	err = bb.(*idbBackend).replace(arecs)
+	if err != nil {
+		// restore the first database, so neither is changed.
+		return errors.Join(err, ab.(*idbBackend).replace(arecs))
+	}
+
+	return nil
+}
+
+// replace every record with the records, clearing the indexes and any staged records, in a single transaction.
+func (b *idbBackend) replace(recs *Records) error {
+	if b.deleted {
+		return ErrDatabaseDeleted
+	}
+
+	names := []string{b.store(bucketStore), b.store(metaStore), b.store(indexStore), b.store(keyStore), b.store(stagingStore)}
+
+	itx, err := b.idb.NewTransaction(names, indexeddb.ReadWriteMode)
+	if err != nil {
+		return err
+	}
+
+	b.trips.transaction()
+
+	for _, nm := range names {
+		err = itx.Store(nm).Clear()
+		if err != nil {
+			return err
+		}
+
+		b.trips.delete(1)
+	}
+
+	meta := itx.Store(b.store(metaStore))
+
+	for k, v := range recs.Meta {
+		err = meta.Put(k, quote(v))
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	// store the buckets the way the records were stored.
+	raw := isRaw(recs.Meta)
+
+	btch := itx.Store(b.store(bucketStore)).Batch()
+
+	for id, v := range recs.Buckets {
+		sv, err := storeValue(id, v, raw)
+		if err != nil {
+			return err
+		}
+
+		err = btch.Put(uint64(id), sv)
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	return btch.Wait()
+}
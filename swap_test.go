@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestSwapDatabases(t *testing.T) {
+	// create 2 databases with a bucket each.
+	for _, nm := range []string{"swap-a.db", "swap-b.db"} {
+		db, err := walletdb.Create("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			bkt, err := tx.CreateTopLevelBucket([]byte("wallet"))
+			if err != nil {
+				return err
+			}
+
+			return bkt.Put([]byte("name"), []byte(nm))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// ensure open databases can't be swapped.
+		if nm == "swap-b.db" {
+			err = SwapDatabases("swap-a.db", nm)
+			if !errors.Is(err, ErrDatabaseOpen) {
+				t.Fatalf("expected %v: got %v", ErrDatabaseOpen, err)
+			}
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := SwapDatabases("swap-a.db", "swap-b.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the contents were exchanged.
+	for nm, exp := range map[string]string{"swap-a.db": "swap-b.db", "swap-b.db": "swap-a.db"} {
+		db, err := walletdb.Open("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			if v := tx.ReadBucket([]byte("wallet")).Get([]byte("name")); string(v) != exp {
+				t.Fatalf("expected %s to contain %s: got %s", nm, exp, v)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure missing databases aren't created.
+	err = SwapDatabases("swap-a.db", "swap-missing.db")
+	if !errors.Is(err, walletdb.ErrDbDoesNotExist) {
+		t.Fatalf("expected %v: got %v", walletdb.ErrDbDoesNotExist, err)
+	}
+}
@@ -0,0 +1,436 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+const (
+	// the metadata key for when every top-level bucket was last modified, see `WithSyncer` and `WithBucketTTL`.
+	modifiedKey = "modified"
+
+	// the metadata key for the time of the remote change every top-level bucket was last merged with.
+	mergedKey = "merged"
+)
+
+// pushing the changes to the syncer failed, the commit is still stored.
+var ErrPush = errors.New("pushing changes failed")
+
+// a change to a top-level bucket, pushed to or pulled from a syncer.
+type SyncChange struct {
+	// the name of the top-level bucket.
+	Bucket []byte
+
+	// an export of the bucket from `ExportBuckets`, nil if the bucket was deleted.
+	Data []byte
+
+	// when the bucket was last modified.
+	Modified time.Time
+}
+
+// a syncer replicates the database to a remote endpoint, such as to share a wallet between devices.
+type Syncer interface {
+	// push local changes to the remote.
+	Push(changes []SyncChange) error
+
+	// pull the remote's changes.
+	Pull() ([]SyncChange, error)
+}
+
+// resolves a key changed on both sides, given the key's local and remote values, nil if the key doesn't exist.
+// it returns the merged value, or nil to delete the key. an error leaves the local value and records a `Conflict`.
+type Resolver func(bucket, key []byte, local, remote []byte) ([]byte, error)
+
+// a key the resolver couldn't resolve, see `Conflicts`.
+type Conflict struct {
+	// the key of the bucket holding the key.
+	Bucket []byte
+
+	Key    []byte
+	Local  []byte
+	Remote []byte
+
+	// the resolver's error.
+	Err error
+}
+
+// push the top-level buckets a commit changes to the syncer, see `Pull` to merge the remote's changes.
+// when each top-level bucket was last modified is stored, a push failing returns an `ErrPush` error from the commit.
+// while the browser is offline the changes are queued in memory and `Pull` fails with `ErrOffline`, the queued
+// changes are pushed once it's back online.
+func WithSyncer(s Syncer) Option {
+	return func(cfg *config) {
+		cfg.syncer = s
+	}
+}
+
+// merge buckets changed both locally and remotely since they were last merged key by key, calling the resolver for
+// keys whose values differ, instead of the last write winning. the merged buckets are pushed like any other change.
+func WithResolver(r Resolver) Option {
+	return func(cfg *config) {
+		cfg.resolver = r
+	}
+}
+
+// pull the syncer's changes and merge them into the database in a single transaction.
+// the last write wins, remote changes to a top-level bucket are only merged if they're newer than the local bucket.
+// with a resolver, buckets changed on both sides since they were last merged are merged key by key instead, see
+// `WithResolver`.
+func (db *DB) Pull() error {
+	if db.cfg.syncer == nil {
+		return errors.New("no syncer is configured")
+	}
+
+	if !online() {
+		return ErrOffline
+	}
+
+	// count the requests, once the changes are merged.
+	stop := db.measure(&db.stats.Pull)
+
+	defer func() {
+		db.lock.Lock()
+		defer db.lock.Unlock()
+
+		stop()
+	}()
+
+	changes, err := db.cfg.syncer.Pull()
+	if err != nil {
+		return err
+	}
+
+	// the newest change to each bucket.
+	newest := make(map[string]SyncChange)
+
+	for _, ch := range changes {
+		if cur, ok := newest[string(ch.Bucket)]; ok && !ch.Modified.After(cur.Modified) {
+			continue
+		}
+
+		newest[string(ch.Bucket)] = ch
+	}
+
+	db.lock.Lock()
+	modified := maps.Clone(db.modified)
+	merged := maps.Clone(db.merged)
+	db.lock.Unlock()
+
+	// the changes which replace the local bucket, and the changes merged key by key.
+	apply := make(map[string]SyncChange)
+	resolve := make(map[string]SyncChange)
+
+	for nm, ch := range newest {
+		// skip changes which are already merged.
+		if db.cfg.resolver != nil && !ch.Modified.After(merged[nm]) {
+			continue
+		}
+
+		// resolve buckets changed on both sides, unless either side deleted it.
+		if db.cfg.resolver != nil && modified[nm].After(merged[nm]) && ch.Data != nil && db.exists(nm) {
+			resolve[nm] = ch
+			continue
+		}
+
+		if ch.Modified.After(modified[nm]) {
+			apply[nm] = ch
+		}
+	}
+
+	if len(apply) == 0 && len(resolve) == 0 {
+		return nil
+	}
+
+	db.lock.Lock()
+
+	// don't push the applied changes back, they're already on the remote.
+	db.pulled = apply
+
+	// mark the changes as merged, so they're stored with the merge.
+	prev := maps.Clone(db.merged)
+
+	for _, chs := range []map[string]SyncChange{apply, resolve} {
+		for nm, ch := range chs {
+			db.merged[nm] = ch.Modified
+		}
+	}
+
+	db.conflicts = nil
+	db.lock.Unlock()
+
+	var conflicts []Conflict
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ttx := tx.(*Transaction)
+
+		// reset the conflicts, in case the transaction is retried.
+		conflicts = nil
+
+		for _, ch := range apply {
+			err := ttx.replace(ch)
+			if err != nil {
+				return err
+			}
+		}
+
+		for nm, ch := range resolve {
+			children, err := readChildren(ch.Data, nil)
+			if err != nil {
+				return fmt.Errorf("bucket %s: %w", nm, err)
+			}
+
+			for _, src := range children[tempdb.RootBucketID] {
+				err = db.resolve(ttx.ReadWriteBucket(src.Key), &src, children, &conflicts)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.pulled = nil
+
+	if err != nil {
+		db.merged = prev
+		return err
+	}
+
+	db.conflicts = conflicts
+
+	return nil
+}
+
+// get the conflicts the resolver couldn't resolve in the last `Pull`, the local values were kept.
+func (db *DB) Conflicts() []Conflict {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return slices.Clone(db.conflicts)
+}
+
+// replace a top-level bucket with the change's export, or delete it if the change has no data.
+func (ttx *Transaction) replace(ch SyncChange) error {
+	ttx.remove(ch.Bucket)
+
+	// the bucket was deleted.
+	if ch.Data == nil {
+		return nil
+	}
+
+	children, err := readChildren(ch.Data, nil)
+	if err != nil {
+		return fmt.Errorf("bucket %s: %w", ch.Bucket, err)
+	}
+
+	return importInto(ttx, children, MergeOverwrite)
+}
+
+// check if a top-level bucket exists.
+func (db *DB) exists(nm string) bool {
+	var ok bool
+
+	walletdb.View(db, func(tx walletdb.ReadTx) error {
+		ok = tx.ReadBucket([]byte(nm)) != nil
+		return nil
+	})
+
+	return ok
+}
+
+// merge a remote bucket into the local bucket key by key, calling the resolver for keys whose values differ.
+// nested buckets are merged the same way, or created if they only exist on the remote.
+func (db *DB) resolve(dst walletdb.ReadWriteBucket, src *tempdb.Bucket, children map[tempdb.BucketID][]tempdb.Bucket, conflicts *[]Conflict) error {
+	// the remote's nested buckets, by key.
+	nested := make(map[string]*tempdb.Bucket)
+
+	for i := range children[src.ID] {
+		nested[string(children[src.ID][i].Key)] = &children[src.ID][i]
+	}
+
+	// the keys on either side, nested buckets are stored as keys with a nil value.
+	keys := make(map[string]bool)
+
+	for k, v := range src.Value {
+		if v != nil {
+			keys[k] = true
+		}
+	}
+
+	err := dst.ForEach(func(k, v []byte) error {
+		if v != nil {
+			keys[string(k)] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for k := range keys {
+		local := dst.Get([]byte(k))
+		remote := src.Value[k]
+
+		if bytes.Equal(local, remote) && (local == nil) == (remote == nil) {
+			continue
+		}
+
+		v, err := db.cfg.resolver(src.Key, []byte(k), local, remote)
+		if err != nil {
+			*conflicts = append(*conflicts, Conflict{
+				Bucket: src.Key,
+				Key:    []byte(k),
+				Local:  bytes.Clone(local),
+				Remote: bytes.Clone(remote),
+				Err:    err,
+			})
+
+			continue
+		}
+
+		if v == nil {
+			err = dst.Delete([]byte(k))
+		} else {
+			err = dst.Put([]byte(k), v)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for k, nb := range nested {
+		if ndst := dst.NestedReadWriteBucket([]byte(k)); ndst != nil {
+			err = db.resolve(ndst, nb, children, conflicts)
+		} else {
+			ndst, err = dst.CreateBucket([]byte(k))
+			if err == nil {
+				err = restore(ndst, nb, children)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mark the top-level buckets as modified, before they're written so the times are stored with them.
+// the lock must be held.
+func (db *DB) touch(changed map[string]bool) {
+	t := db.cfg.now()
+
+	for nm := range changed {
+		// use the remote's time for merged buckets.
+		if ch, ok := db.pulled[nm]; ok {
+			db.modified[nm] = ch.Modified
+			continue
+		}
+
+		db.modified[nm] = t
+	}
+}
+
+// push the changed top-level buckets to the syncer, skipping merged buckets. the lock must be held.
+// while the browser is offline the buckets are queued, and pushed with the next push once it's online.
+func (db *DB) push(changed map[string]bool) error {
+	for nm := range changed {
+		// merged buckets are already on the remote.
+		if _, ok := db.pulled[nm]; !ok {
+			db.queued[nm] = true
+		}
+	}
+
+	if !online() {
+		return nil
+	}
+
+	var changes []SyncChange
+
+	for nm := range db.queued {
+		ch, err := db.change(nm)
+		if err != nil {
+			return err
+		}
+
+		changes = append(changes, ch)
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err := db.cfg.syncer.Push(changes)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPush, err)
+	}
+
+	clear(db.queued)
+
+	return nil
+}
+
+// get the change to a top-level bucket, exporting it unless it was deleted. the lock must be held.
+func (db *DB) change(nm string) (SyncChange, error) {
+	ch := SyncChange{
+		Bucket:   []byte(nm),
+		Modified: db.modified[nm],
+	}
+
+	exists := slices.ContainsFunc(db.State.Buckets, func(bkt tempdb.Bucket) bool {
+		return bkt.Parent == tempdb.RootBucketID && string(bkt.Key) == nm
+	})
+
+	if exists {
+		buf := new(bytes.Buffer)
+
+		err := db.exportTo(buf, [][]byte{[]byte(nm)}, 0)
+		if err != nil {
+			return SyncChange{}, err
+		}
+
+		ch.Data = buf.Bytes()
+	}
+
+	return ch, nil
+}
+
+// encode the time of every top-level bucket to be stored, encrypting it if configured so the names aren't stored in
+// plaintext.
+func (cfg *config) encodeTimes(times map[string]time.Time) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	err := gob.NewEncoder(buf).Encode(times)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.encrypt(buf.Bytes())
+}
+
+// decode the stored time of every top-level bucket, decrypting them if configured.
+func (cfg *config) decodeTimes(v []byte, times *map[string]time.Time) error {
+	v, err := cfg.decrypt(v)
+	if err != nil {
+		return err
+	}
+
+	return gobDecode(v, times)
+}
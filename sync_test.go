@@ -0,0 +1,362 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// a syncer which keeps the remote's changes in memory.
+type memorySyncer struct {
+	pushed []SyncChange
+	remote []SyncChange
+}
+
+func (s *memorySyncer) Push(changes []SyncChange) error {
+	s.pushed = append(s.pushed, changes...)
+	return nil
+}
+
+func (s *memorySyncer) Pull() ([]SyncChange, error) {
+	return s.remote, nil
+}
+
+// put a value in a top-level bucket, creating it if it doesn't exist.
+func putValue(t *testing.T, db walletdb.DB, nm, v string) {
+	err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt := tx.ReadWriteBucket([]byte(nm))
+
+		if bkt == nil {
+			var err error
+
+			bkt, err = tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+		}
+
+		return bkt.Put([]byte("key"), []byte(v))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// get the value in a top-level bucket.
+func getValue(t *testing.T, db walletdb.DB, nm string) string {
+	var v []byte
+
+	err := walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if bkt := tx.ReadBucket([]byte(nm)); bkt != nil {
+			v = bkt.Get([]byte("key"))
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(v)
+}
+
+func TestSync(t *testing.T) {
+	// the name of the database.
+	nm := "sync.db"
+
+	// control the time buckets are modified at.
+	var clock time.Time
+
+	clk := WithClock(func() time.Time { return clock })
+
+	at := func(ms int64) time.Time {
+		return time.UnixMilli(ms)
+	}
+
+	s := &memorySyncer{}
+
+	db, err := walletdb.Create("localdb", nm, WithSyncer(s), clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure local changes are pushed.
+	clock = at(1000)
+	putValue(t, db, "a", "local")
+
+	if len(s.pushed) != 1 || string(s.pushed[0].Bucket) != "a" || !s.pushed[0].Modified.Equal(clock) {
+		t.Fatalf("expected a to be pushed at %v: got %v", clock, s.pushed)
+	}
+
+	info, err := VerifyExport(s.pushed[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Buckets != 1 {
+		t.Fatalf("expected 1 bucket to be pushed: got %d", info.Buckets)
+	}
+
+	// export the buckets of another device.
+	remote := func(nm, v string) []byte {
+		rdb, err := walletdb.Create("localdb", "remote-"+nm+"-"+v+".db")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		putValue(t, rdb, nm, v)
+
+		data, err := rdb.(*DB).ExportBuckets([]byte(nm))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return data
+	}
+
+	// a remote change to a new bucket, and an older conflicting change.
+	s.remote = []SyncChange{
+		{Bucket: []byte("b"), Data: remote("b", "remote"), Modified: at(2000)},
+		{Bucket: []byte("a"), Data: remote("a", "older"), Modified: at(500)},
+	}
+
+	clock = at(2500)
+
+	err = db.(*DB).Pull()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "b"); v != "remote" {
+		t.Fatalf("expected remote: got %s", v)
+	}
+
+	if v := getValue(t, db, "a"); v != "local" {
+		t.Fatalf("expected the newer local change to win: got %s", v)
+	}
+
+	// ensure merged changes aren't pushed back.
+	if len(s.pushed) != 1 {
+		t.Fatalf("expected nothing else to be pushed: got %v", s.pushed)
+	}
+
+	// a newer conflicting change wins.
+	s.remote = []SyncChange{
+		{Bucket: []byte("a"), Data: remote("a", "newer"), Modified: at(3000)},
+	}
+
+	err = db.(*DB).Pull()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "a"); v != "newer" {
+		t.Fatalf("expected the newer remote change to win: got %s", v)
+	}
+
+	// ensure the times are stored, so an older change still loses after reopening.
+	db.Close()
+
+	db, err = walletdb.Open("localdb", nm, WithSyncer(s), clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.remote = []SyncChange{
+		{Bucket: []byte("a"), Data: remote("a", "stale"), Modified: at(2900)},
+	}
+
+	err = db.(*DB).Pull()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "a"); v != "newer" {
+		t.Fatalf("expected newer: got %s", v)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	// control the time buckets are modified at.
+	var clock time.Time
+
+	clk := WithClock(func() time.Time { return clock })
+
+	s := &memorySyncer{}
+
+	// join conflicting values, failing for the key named "fail".
+	resolve := func(bucket, key, local, remote []byte) ([]byte, error) {
+		if string(key) == "fail" {
+			return nil, errors.New("unresolvable")
+		}
+
+		return []byte(string(local) + "+" + string(remote)), nil
+	}
+
+	db, err := walletdb.Create("localdb", "resolver.db", WithSyncer(s), WithResolver(resolve), clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	clock = time.UnixMilli(1000)
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("a"))
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"key", "fail", "same"} {
+			err = bkt.Put([]byte(k), []byte("local"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// export a conflicting change from another device.
+	rdb, err := walletdb.Create("localdb", "resolver-remote.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rdb.Close()
+
+	err = walletdb.Update(rdb, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("a"))
+		if err != nil {
+			return err
+		}
+
+		for k, v := range map[string]string{"key": "remote", "fail": "remote", "same": "local"} {
+			err = bkt.Put([]byte(k), []byte(v))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := rdb.(*DB).ExportBuckets([]byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the remote change is newer, so it would win without a resolver.
+	s.remote = []SyncChange{
+		{Bucket: []byte("a"), Data: data, Modified: time.UnixMilli(2000)},
+	}
+
+	clock = time.UnixMilli(3000)
+
+	err = db.(*DB).Pull()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "a"); v != "local+remote" {
+		t.Fatalf("expected the resolver's value: got %s", v)
+	}
+
+	// ensure the unresolved key is recorded and keeps the local value.
+	cs := db.(*DB).Conflicts()
+
+	if len(cs) != 1 || string(cs[0].Key) != "fail" || string(cs[0].Local) != "local" || string(cs[0].Remote) != "remote" {
+		t.Fatalf("expected a conflict for fail: got %v", cs)
+	}
+
+	// ensure the merge is pushed.
+	if n := len(s.pushed); n != 2 || !s.pushed[n-1].Modified.Equal(clock) {
+		t.Fatalf("expected the merge to be pushed: got %v", s.pushed)
+	}
+
+	// ensure the same change isn't merged twice.
+	err = db.(*DB).Pull()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "a"); v != "local+remote" {
+		t.Fatalf("expected the change to be merged once: got %s", v)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	// mock the browser going offline.
+	orig := online
+	defer func() { online = orig }()
+
+	var offline atomic.Bool
+	online = func() bool { return !offline.Load() }
+
+	s := &memorySyncer{}
+
+	db, err := walletdb.Create("localdb", "offline.db", WithSyncer(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	offline.Store(true)
+
+	putValue(t, db, "wallet", "keys")
+	putValue(t, db, "wallet", "more keys")
+
+	// ensure sync is paused.
+	if len(s.pushed) != 0 {
+		t.Fatalf("expected no pushes while offline: got %d", len(s.pushed))
+	}
+
+	err = db.(*DB).Pull()
+	if !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected %v: got %v", ErrOffline, err)
+	}
+
+	// go back online, the queued changes are pushed in the background.
+	offline.Store(false)
+	js.Global().Call("dispatchEvent", js.Global().Get("Event").New("online"))
+
+	for i := 0; i < 100; i++ {
+		db.(*DB).lock.Lock()
+		n := len(s.pushed)
+		db.(*DB).lock.Unlock()
+
+		if n > 0 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// ensure the queued changes were pushed once, with the latest value.
+	if len(s.pushed) != 1 {
+		t.Fatalf("expected 1 push: got %d", len(s.pushed))
+	}
+
+	bkts, err := readAll(s.pushed[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := string(bkts[0].Value["key"]); v != "more keys" {
+		t.Fatalf("expected more keys: got %q", v)
+	}
+}
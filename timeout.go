@@ -0,0 +1,108 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"syscall/js"
+	"time"
+)
+
+// the database wasn't loaded within the timeout, see `WithOpenTimeout`.
+var ErrOpenTimeout = errors.New("loading the database timed out")
+
+// fail opening a database with `ErrOpenTimeout` if loading it takes longer than d, cancelling the load.
+// indexeddb can hang under memory pressure, this lets the app fall back to a degraded mode instead of waiting.
+func WithOpenTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.openTimeout = d
+	}
+}
+
+// a backend whose in-flight load can be cancelled.
+type aborter interface {
+	abort()
+}
+
+// load every record, failing if it takes longer than the open timeout.
+func (db *DB) loadWithin() (*Records, error) {
+	if db.cfg.openTimeout <= 0 {
+		return db.cfg.load(db.backend)
+	}
+
+	type result struct {
+		recs *Records
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		// the backend is closed once the load times out, so a late load may throw.
+		defer func() {
+			if r := recover(); r != nil {
+				jerr, ok := r.(js.Error)
+				if !ok {
+					panic(r)
+				}
+
+				done <- result{err: jerr}
+			}
+		}()
+
+		recs, err := db.cfg.load(db.backend)
+		done <- result{recs, err}
+	}()
+
+	// time the load with the browser's timer.
+	expired := make(chan struct{})
+
+	fire := js.FuncOf(func(this js.Value, args []js.Value) any {
+		close(expired)
+		return nil
+	})
+
+	defer fire.Release()
+
+	id := js.Global().Call("setTimeout", fire, db.cfg.openTimeout.Milliseconds())
+	defer js.Global().Call("clearTimeout", id)
+
+	select {
+	case res := <-done:
+		return res.recs, res.err
+
+	case <-expired:
+		// cancel the read, so the connection can be closed.
+		if a, ok := db.backend.(aborter); ok {
+			a.abort()
+		}
+
+		db.backend.Close()
+
+		return nil, ErrOpenTimeout
+	}
+}
+
+// abort the in-flight load, if there is one.
+func (b *idbBackend) abort() {
+	if b.loading == nil {
+		return
+	}
+
+	// aborting a finished transaction throws.
+	defer func() {
+		if r := recover(); r != nil {
+			if _, js := r.(js.Error); !js {
+				panic(r)
+			}
+		}
+	}()
+
+	value(b.loading).Call("abort")
+}
+
+func (b *sharedBackend) abort() {
+	if b.b != nil {
+		b.b.abort()
+	}
+}
@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"maps"
+	"time"
+
+	"github.com/linden/tempdb"
+)
+
+const (
+	// the metadata key for when every deleted top-level bucket was deleted.
+	tombstonesKey = "tombstones"
+
+	// how long tombstones are kept by default, see `WithTombstoneRetention`.
+	tombstoneRetention = 30 * 24 * time.Hour
+)
+
+// keep the tombstones of deleted top-level buckets for d, 30 days by default, see `Tombstones`.
+// once a tombstone is collected a peer which hasn't synced since can bring the bucket back.
+func WithTombstoneRetention(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.tombstoneRetention = d
+	}
+}
+
+// get when every deleted top-level bucket was deleted, by name.
+// a tombstone is stored when a top-level bucket is deleted while modified times are tracked, see `WithSyncer`, so the
+// deletion is pushed and merged instead of the bucket being brought back by an older remote change.
+// it's removed when the bucket is created again, or collected once the retention passes.
+func (db *DB) Tombstones() map[string]time.Time {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return maps.Clone(db.tombstones)
+}
+
+// store a tombstone for the changed top-level buckets which were deleted, removing them for the buckets which exist.
+// the buckets must be touched first, the lock must be held.
+func (db *DB) bury(changed map[string]bool, next []tempdb.Bucket) {
+	// the top-level buckets which exist.
+	exist := make(map[string]bool)
+
+	for _, bkt := range next {
+		if bkt.Parent == tempdb.RootBucketID {
+			exist[string(bkt.Key)] = true
+		}
+	}
+
+	for nm := range changed {
+		if exist[nm] {
+			delete(db.tombstones, nm)
+			continue
+		}
+
+		db.tombstones[nm] = db.modified[nm]
+	}
+
+	db.collect()
+}
+
+// remove the tombstones older than the retention, forgetting when their buckets were modified and merged.
+// the lock must be held.
+func (db *DB) collect() {
+	retention := db.cfg.tombstoneRetention
+	if retention <= 0 {
+		retention = tombstoneRetention
+	}
+
+	t := db.cfg.now()
+
+	for nm, deleted := range db.tombstones {
+		if t.After(deleted.Add(retention)) {
+			delete(db.tombstones, nm)
+			delete(db.modified, nm)
+			delete(db.merged, nm)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestTombstones(t *testing.T) {
+	// the name of the database.
+	nm := "tombstone.db"
+
+	// control the time buckets are deleted at.
+	clock := time.UnixMilli(1000)
+
+	clk := WithClock(func() time.Time { return clock })
+	opts := []any{WithSyncer(&memorySyncer{}), WithTombstoneRetention(time.Hour), clk}
+
+	db, err := walletdb.Create("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "wallet", "keys")
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		return tx.DeleteTopLevelBucket([]byte("wallet"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// ensure the tombstone is kept.
+	clock = clock.Add(30 * time.Minute)
+
+	db, err = walletdb.Open("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := db.(*DB).Tombstones()
+	if deleted, ok := ts["wallet"]; !ok || !deleted.Equal(time.UnixMilli(1000)) {
+		t.Fatalf("expected a tombstone from %v: got %v", time.UnixMilli(1000), ts)
+	}
+
+	db.Close()
+
+	// ensure the tombstone is collected once the retention passes.
+	clock = clock.Add(time.Hour)
+
+	db, err = walletdb.Open("localdb", append([]any{nm}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if ts := db.(*DB).Tombstones(); len(ts) != 0 {
+		t.Fatalf("expected the tombstone to be collected: got %v", ts)
+	}
+
+	// ensure creating the bucket again doesn't leave a tombstone.
+	putValue(t, db, "wallet", "keys")
+
+	if ts := db.(*DB).Tombstones(); len(ts) != 0 {
+		t.Fatalf("expected no tombstones: got %v", ts)
+	}
+}
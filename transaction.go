@@ -0,0 +1,159 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+var (
+	// the savepoint belongs to another transaction.
+	ErrSavepoint = errors.New("savepoint belongs to another transaction")
+
+	// creating the top-level bucket would exceed the limit, see `WithMaxBuckets`.
+	ErrTooManyBuckets = errors.New("too many top-level buckets")
+)
+
+// Ensure `Transaction` complies with the `walletdb.ReadWriteTx` interface.
+var _ walletdb.ReadWriteTx = (*Transaction)(nil)
+
+// a read/write transaction, returned by `BeginReadWriteTx`.
+type Transaction struct {
+	*tempdb.Transaction
+
+	// the error flushing the transaction, set on commit.
+	err error
+
+	// the database's config and the buckets from before the transaction, to validate the values.
+	cfg  *config
+	prev []tempdb.Bucket
+
+	// how the transaction is flushed, see `UpdateWith`.
+	opts UpdateOptions
+}
+
+// commit the transaction and flush it, if the flush fails an `ErrNotFlushed` error is returned.
+// if a value is rejected by a validator the transaction is rolled back, see `WithValidator`.
+func (tx *Transaction) Commit() error {
+	if tx.cfg != nil && !tx.Rolledback {
+		err := tx.cfg.validate(tx.prev, tx.State.Buckets)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err := tx.Transaction.Commit()
+	if err != nil {
+		return err
+	}
+
+	return tx.err
+}
+
+// add a hook called once the transaction is committed, hooks are called in the order they're added.
+// the flush is added when the transaction begins, so every hook is called after the changes are written or failed to be.
+// the state is committed before any hook is called, so a hook can't fail the commit, see `Err` for the flush's error.
+func (tx *Transaction) OnCommit(fn func()) {
+	tx.Transaction.OnCommit(fn)
+}
+
+// get the error flushing the transaction, nil until it's committed, so a commit hook can tell if the flush failed.
+func (tx *Transaction) Err() error {
+	return tx.err
+}
+
+// create a top-level bucket, or get it if it exists, failing with `ErrTooManyBuckets` once the limit is reached.
+func (tx *Transaction) CreateTopLevelBucket(key []byte) (walletdb.ReadWriteBucket, error) {
+	if tx.cfg != nil && tx.cfg.maxBuckets > 0 && tx.ReadWriteBucket(key) == nil {
+		var n int
+
+		for _, bkt := range tx.State.Buckets {
+			if bkt.Parent == tempdb.RootBucketID {
+				n++
+			}
+		}
+
+		if n >= tx.cfg.maxBuckets {
+			return nil, fmt.Errorf("%w: the limit is %d", ErrTooManyBuckets, tx.cfg.maxBuckets)
+		}
+	}
+
+	return tx.Transaction.CreateTopLevelBucket(key)
+}
+
+// a point in a transaction that it can be rolled back to.
+type Savepoint struct {
+	tx    *Transaction
+	state *tempdb.State
+}
+
+// mark the current state of the transaction, so later changes can be undone with `RollbackTo`.
+// the transaction is in memory until it's committed, so a savepoint is a copy of its state.
+func (tx *Transaction) Savepoint() *Savepoint {
+	return &Savepoint{
+		tx:    tx,
+		state: snapshot(tx.State),
+	}
+}
+
+// undo every change made since the savepoint, without ending the transaction.
+// buckets created after the savepoint no longer exist and must not be used.
+func (tx *Transaction) RollbackTo(sp *Savepoint) error {
+	if tx.Rolledback {
+		return walletdb.ErrTxClosed
+	}
+
+	if sp.tx != tx {
+		return ErrSavepoint
+	}
+
+	// the current values of every bucket, by ID.
+	cur := make(map[tempdb.BucketID]map[string][]byte)
+
+	for _, bkt := range tx.State.Buckets {
+		cur[bkt.ID] = bkt.Value
+	}
+
+	bkts := make([]tempdb.Bucket, len(sp.state.Buckets))
+
+	for i, bkt := range sp.state.Buckets {
+		// restore the values in place, so buckets from before the savepoint can still be used.
+		if v, ok := cur[bkt.ID]; ok {
+			clear(v)
+			maps.Copy(v, bkt.Value)
+			bkt.Value = v
+		} else {
+			bkt.Value = maps.Clone(bkt.Value)
+		}
+
+		bkts[i] = bkt
+	}
+
+	// restore the state, including the next bucket ID.
+	state := *sp.state
+	state.Buckets = bkts
+
+	*tx.State = state
+
+	return nil
+}
+
+// copy a transaction's state.
+// unlike `tempdb.State.Copy` the buckets keep their transaction, so they can still be written to.
+func snapshot(s *tempdb.State) *tempdb.State {
+	cpy := *s
+	cpy.Buckets = make([]tempdb.Bucket, len(s.Buckets))
+
+	for i, bkt := range s.Buckets {
+		bkt.Value = maps.Clone(bkt.Value)
+		cpy.Buckets[i] = bkt
+	}
+
+	return &cpy
+}
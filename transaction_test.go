@@ -0,0 +1,231 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestSavepoint(t *testing.T) {
+	// the name of the database.
+	nm := "savepoint.db"
+	db, err := walletdb.Create("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Put([]byte("before"), []byte("1"))
+		if err != nil {
+			return err
+		}
+
+		sp := tx.(*Transaction).Savepoint()
+
+		// change the bucket and create another, after the savepoint.
+		err = bkt.Put([]byte("after"), []byte("2"))
+		if err != nil {
+			return err
+		}
+
+		err = bkt.Delete([]byte("before"))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateTopLevelBucket([]byte("other"))
+		if err != nil {
+			return err
+		}
+
+		err = tx.(*Transaction).RollbackTo(sp)
+		if err != nil {
+			return err
+		}
+
+		// the bucket can still be used after rolling back.
+		return bkt.Put([]byte("rolledback"), []byte("3"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure only the changes from before the savepoint, and after rolling back, are stored.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		if tx.ReadBucket([]byte("other")) != nil {
+			t.Fatal("expected the bucket created after the savepoint not to exist")
+		}
+
+		bkt := tx.ReadBucket([]byte("bucket"))
+
+		for k, v := range map[string][]byte{"before": []byte("1"), "after": nil, "rolledback": []byte("3")} {
+			if got := bkt.Get([]byte(k)); !bytes.Equal(got, v) {
+				t.Fatalf("expected %s to be %s: got %s", k, v, got)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidator(t *testing.T) {
+	// reject values that aren't numbers.
+	numeric := func(key, value []byte) error {
+		for _, c := range value {
+			if c < '0' || c > '9' {
+				return errors.New("not a number")
+			}
+		}
+
+		return nil
+	}
+
+	db, err := walletdb.Create("localdb", "validator.db", WithValidator([]byte("balances"), numeric))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("balances"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("alice"), []byte("100"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure a rejected value fails the transaction, including its valid values.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt := tx.ReadWriteBucket([]byte("balances"))
+
+		err := bkt.Put([]byte("bob"), []byte("200"))
+		if err != nil {
+			return err
+		}
+
+		return bkt.Put([]byte("carol"), []byte("lots"))
+	})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("expected %v: got %v", ErrInvalidValue, err)
+	}
+
+	err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+		bkt := tx.ReadBucket([]byte("balances"))
+
+		if bkt.Get([]byte("bob")) != nil || bkt.Get([]byte("carol")) != nil {
+			t.Fatal("expected the transaction to be rolled back")
+		}
+
+		if v := string(bkt.Get([]byte("alice"))); v != "100" {
+			t.Fatalf("expected 100: got %s", v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure other buckets aren't validated.
+	putValue(t, db, "other", "anything")
+}
+
+func TestOnCommit(t *testing.T) {
+	var cb *crashingBackend
+
+	crashing := func(name string) (Backend, bool, error) {
+		b, exist, err := IndexedDB(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		cb = &crashingBackend{Backend: b, after: math.MaxInt}
+		return cb, exist, nil
+	}
+
+	db, err := walletdb.Create("localdb", "oncommit.db", WithBackend(crashing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	// commit a transaction with hooks, returning the order they were called in and the flush error they saw.
+	commit := func() ([]int, error, error) {
+		tx, err := db.BeginReadWriteTx()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var order []int
+		var seen error
+
+		// the writes the flush made before the hooks were called.
+		writes := cb.writes
+
+		for i := 0; i < 2; i++ {
+			i := i
+
+			tx.(*Transaction).OnCommit(func() {
+				if cb.writes == writes {
+					t.Error("expected the hook to be called after the flush")
+				}
+
+				order = append(order, i)
+				seen = tx.(*Transaction).Err()
+			})
+		}
+
+		_, err = tx.CreateTopLevelBucket([]byte{byte(cb.writes)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return order, seen, tx.Commit()
+	}
+
+	order, seen, err := commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(order, []int{0, 1}) {
+		t.Fatalf("expected the hooks to be called in order: got %v", order)
+	}
+
+	if seen != nil {
+		t.Fatalf("expected no flush error: got %v", seen)
+	}
+
+	// ensure the hooks see a failed flush.
+	cb.after = cb.writes
+
+	_, seen, err = commit()
+	if !errors.Is(err, ErrNotFlushed) || !errors.Is(seen, ErrNotFlushed) {
+		t.Fatalf("expected %v: got %v, and %v in the hook", ErrNotFlushed, err, seen)
+	}
+}
@@ -0,0 +1,98 @@
+//go:build js && wasm
+
+package localdb
+
+// the indexeddb requests made by an operation, see `WithRoundTrips`.
+// records are read with `getAll`, so there are no count or cursor requests.
+type RoundTrips struct {
+	Transactions int
+
+	// the get requests, including `getAll` and `getAllKeys`.
+	Gets int
+
+	Puts    int
+	Deletes int
+}
+
+// the indexeddb requests made by the last of each operation, see `WithRoundTrips`.
+type OperationStats struct {
+	// opening the database, including rewriting stale records.
+	Open RoundTrips
+
+	// flushing a committed transaction, including backups.
+	Update RoundTrips
+
+	// flushing deferred buckets with `Flush`.
+	Flush RoundTrips
+
+	// merging a syncer's changes with `Pull`, including its transaction.
+	Pull RoundTrips
+}
+
+// count the indexeddb requests every operation makes, to find operations making more requests than they need.
+// this is meant for debugging, see `RoundTrips` to get the counts. requests made by other backends aren't counted.
+func WithRoundTrips() Option {
+	return func(cfg *config) {
+		cfg.roundTrips = true
+	}
+}
+
+// a backend which counts its indexeddb requests.
+type tripCounter interface {
+	countTrips(t *RoundTrips)
+}
+
+// get the indexeddb requests made by the last of each operation.
+func (db *DB) RoundTrips() OperationStats {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.stats
+}
+
+// count the requests made until the returned function is called, storing them in op.
+// operations can be nested, every request is counted by both.
+func (db *DB) measure(op *RoundTrips) func() {
+	if db.trips == nil {
+		return func() {}
+	}
+
+	start := *db.trips
+
+	return func() {
+		*op = RoundTrips{
+			Transactions: db.trips.Transactions - start.Transactions,
+			Gets:         db.trips.Gets - start.Gets,
+			Puts:         db.trips.Puts - start.Puts,
+			Deletes:      db.trips.Deletes - start.Deletes,
+		}
+	}
+}
+
+// count a transaction, if requests are counted.
+func (t *RoundTrips) transaction() {
+	if t != nil {
+		t.Transactions++
+	}
+}
+
+// count get requests, if requests are counted.
+func (t *RoundTrips) get(n int) {
+	if t != nil {
+		t.Gets += n
+	}
+}
+
+// count put requests, if requests are counted.
+func (t *RoundTrips) put(n int) {
+	if t != nil {
+		t.Puts += n
+	}
+}
+
+// count delete requests, if requests are counted.
+func (t *RoundTrips) delete(n int) {
+	if t != nil {
+		t.Deletes += n
+	}
+}
@@ -0,0 +1,44 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestRoundTrips(t *testing.T) {
+	// the name of the database.
+	nm := "trips.db"
+
+	db, err := walletdb.Create("localdb", nm, WithRoundTrips())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "bucket", "value")
+
+	// 1 transaction getting the storage mode, putting the count, schema and bucket.
+	exp := RoundTrips{Transactions: 1, Gets: 1, Puts: 3}
+
+	if st := db.(*DB).RoundTrips(); st.Update != exp {
+		t.Fatalf("expected %+v: got %+v", exp, st.Update)
+	}
+
+	db.Close()
+
+	db, err = walletdb.Open("localdb", nm, WithRoundTrips())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	// 1 transaction getting the keys and values of the metadata and buckets.
+	exp = RoundTrips{Transactions: 1, Gets: 4}
+
+	if st := db.(*DB).RoundTrips(); st.Open != exp {
+		t.Fatalf("expected %+v: got %+v", exp, st.Open)
+	}
+}
@@ -0,0 +1,74 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"slices"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+// expire the top-level bucket once ttl has passed since it was last modified, for cache-like data such as fee
+// estimates. expired buckets are emptied when the database is opened, deleting their keys and nested buckets.
+// the option can be passed for every bucket that expires.
+func WithBucketTTL(name []byte, ttl time.Duration) Option {
+	return func(cfg *config) {
+		if cfg.ttls == nil {
+			cfg.ttls = make(map[string]time.Duration)
+		}
+
+		cfg.ttls[string(name)] = ttl
+	}
+}
+
+// check if the modified times of the top-level buckets are tracked, for the syncer and expiry.
+func (cfg *config) timed() bool {
+	return cfg.syncer != nil || len(cfg.ttls) > 0
+}
+
+// empty the expired top-level buckets in a single transaction.
+func (db *DB) expire() error {
+	var expired [][]byte
+
+	t := db.cfg.now()
+
+	db.lock.Lock()
+
+	for nm, ttl := range db.cfg.ttls {
+		if mod, ok := db.modified[nm]; ok && t.After(mod.Add(ttl)) {
+			expired = append(expired, []byte(nm))
+		}
+	}
+
+	db.lock.Unlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	// sort the names, so the buckets are recreated in a stable order.
+	slices.SortFunc(expired, bytes.Compare)
+
+	return walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		ttx := tx.(*Transaction)
+
+		for _, nm := range expired {
+			// the bucket may have been deleted since it was modified.
+			if ttx.ReadWriteBucket(nm) == nil {
+				continue
+			}
+
+			// remove the bucket along with every bucket nested in it.
+			ttx.remove(nm)
+
+			_, err := ttx.CreateTopLevelBucket(nm)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,108 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+)
+
+func TestBucketTTL(t *testing.T) {
+	// the name of the database.
+	nm := "ttl.db"
+
+	// control the time buckets are modified at.
+	clock := time.UnixMilli(1000)
+
+	clk := WithClock(func() time.Time { return clock })
+	opt := WithBucketTTL([]byte("cache"), time.Hour)
+
+	db, err := walletdb.Create("localdb", nm, opt, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putValue(t, db, "cache", "fee estimate")
+	putValue(t, db, "wallet", "keys")
+
+	// nest a bucket in the expiring bucket, with a bucket nested in it.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.ReadWriteBucket([]byte("cache")).CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+
+		nbkt, err := bkt.CreateBucket([]byte("deeper"))
+		if err != nil {
+			return err
+		}
+
+		return nbkt.Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Close()
+
+	// reopen before the bucket expires.
+	clock = clock.Add(30 * time.Minute)
+
+	db, err = walletdb.Open("localdb", nm, opt, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "cache"); v != "fee estimate" {
+		t.Fatalf("expected the bucket to be kept: got %q", v)
+	}
+
+	db.Close()
+
+	// reopen once the bucket expires.
+	clock = clock.Add(time.Hour)
+
+	db, err = walletdb.Open("localdb", nm, opt, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := getValue(t, db, "cache"); v != "" {
+		t.Fatalf("expected the bucket to expire: got %q", v)
+	}
+
+	if v := getValue(t, db, "wallet"); v != "keys" {
+		t.Fatalf("expected wallet to be kept: got %q", v)
+	}
+
+	db.Close()
+
+	// ensure the expired bucket's storage is cleaned up.
+	db, err = walletdb.Open("localdb", nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	if v := getValue(t, db, "cache"); v != "" {
+		t.Fatalf("expected the expired bucket to be stored empty: got %q", v)
+	}
+
+	// ensure the nested buckets expired with it.
+	bkts, keys, err := db.(*DB).Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bkts != 2 || keys != 1 {
+		t.Fatalf("expected 2 buckets and 1 key: got %d buckets and %d keys", bkts, keys)
+	}
+
+	err = db.(*DB).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"slices"
+
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+// flush in 2 phases when a flush takes more than 1 write, such as with `WithBatchSize`. the records are first written
+// to a staging store in batches, then promoted into the bucket store along with the deletes and metadata in a single
+// transaction, so the stored database is never partially updated. a flush interrupted before it's promoted leaves the
+// previous state, unlike `WithJournal` which finishes the flush on open.
+// every record is written twice, and promoting copies every staged record in 1 transaction, so the browser can still
+// close a large promotion, batching only limits the transactions which stage. requires the indexeddb backend.
+func WithTwoPhaseFlush() Option {
+	return func(cfg *config) {
+		cfg.twoPhase = true
+	}
+}
+
+// write the changes in 2 phases, staging the records in batches of size then promoting them with everything else.
+func (b *idbBackend) writeStaged(ch *Changes, size int) error {
+	ids := make([]tempdb.BucketID, 0, len(ch.Buckets))
+
+	for id := range ch.Buckets {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	for i := 0; i < len(ids); i += size {
+		btch := make(map[tempdb.BucketID][]byte)
+
+		for _, id := range ids[i:min(i+size, len(ids))] {
+			btch[id] = ch.Buckets[id]
+		}
+
+		// clear the records staged by an interrupted flush with the first batch.
+		err := b.stage(ch, btch, i == 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	// promote the staged records with the rest of the changes.
+	promote := *ch
+	promote.Buckets = nil
+
+	return b.write(&promote, true)
+}
+
+// write the records to the staging store, stored the way they will be in the bucket store.
+func (b *idbBackend) stage(ch *Changes, recs map[tempdb.BucketID][]byte, clear bool) error {
+	if b.deleted {
+		return ErrDatabaseDeleted
+	}
+
+	itx, err := b.idb.NewTransaction([]string{b.store(stagingStore), b.store(metaStore)}, indexeddb.ReadWriteMode)
+	if err != nil {
+		return err
+	}
+
+	b.trips.transaction()
+
+	stg := itx.Store(b.store(stagingStore))
+
+	if clear {
+		err = stg.Clear()
+		if err != nil {
+			return err
+		}
+
+		b.trips.delete(1)
+	}
+
+	// find how the buckets are stored.
+	raw, err := b.raw(itx.Store(b.store(metaStore)), &Changes{Records: Records{Buckets: recs, Meta: ch.Meta}})
+	if err != nil {
+		return err
+	}
+
+	btch := stg.Batch()
+
+	for id, v := range recs {
+		var sv any
+
+		if ch.Blobs[id] {
+			sv = newBlob(v)
+		} else {
+			sv, err = storeValue(id, v, raw)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = btch.Put(uint64(id), sv)
+		if err != nil {
+			return err
+		}
+
+		b.trips.put(1)
+	}
+
+	return btch.Wait()
+}
@@ -0,0 +1,106 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/indexeddb"
+	"github.com/linden/tempdb"
+)
+
+func TestTwoPhaseFlush(t *testing.T) {
+	// the name of the database.
+	nm := "two-phase.db"
+	db, err := walletdb.Create("localdb", nm, WithBatchSize(1), WithTwoPhaseFlush())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// put the value in 3 buckets, so the flush is staged.
+	put := func(v string) {
+		err := walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+			for i := 0; i < 3; i++ {
+				bkt, err := tx.CreateTopLevelBucket([]byte(fmt.Sprintf("bucket-%d", i)))
+				if err != nil {
+					return err
+				}
+
+				err = bkt.Put([]byte("key"), []byte(v))
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure every bucket has the value once reopened.
+	check := func(v string) {
+		db, err := walletdb.Open("localdb", nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			for i := 0; i < 3; i++ {
+				if got := tx.ReadBucket([]byte(fmt.Sprintf("bucket-%d", i))).Get([]byte("key")); string(got) != v {
+					t.Fatalf("expected bucket %d to have %s: got %s", i, v, got)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	put("old")
+	check("old")
+
+	// ensure the staging store is emptied once the flush is promoted.
+	ib := indexedDB(db.(*DB).backend)
+
+	itx, err := db.(*DB).RawTransaction([]string{stagingStore}, indexeddb.ReadMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := itx.Store(stagingStore).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 0 {
+		t.Fatalf("expected nothing to be staged: got %d records", n)
+	}
+
+	// interrupt a flush between the phases, by only staging its records.
+	recs := make(map[tempdb.BucketID][]byte)
+
+	for id := range db.(*DB).records {
+		recs[id] = []byte("new")
+	}
+
+	// a record the later flush doesn't have, which would fail the count if it was promoted.
+	recs[999] = []byte("new")
+
+	err = ib.stage(&Changes{}, recs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the previous state survives.
+	check("old")
+
+	// ensure a later flush doesn't promote the interrupted flush's records.
+	put("new")
+	check("new")
+}
@@ -0,0 +1,150 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+// a stored value is missing or doesn't match the database, see `ValidateBucket`.
+var ErrCorruptKey = errors.New("stored value is missing or corrupt")
+
+// check that every key in a top-level bucket is stored, decoding its stored records and comparing every key to the
+// database. it returns the first key which is missing or corrupt, wrapped in `ErrCorruptKey`, or the record which
+// can't be decoded. stored records the database doesn't know about are decoded too, one in the bucket is corrupt.
+// this is cheaper than opening the database again, but every record is still loaded.
+func (db *DB) ValidateBucket(name []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	// pending changes aren't stored yet.
+	if db.dirty[string(name)] {
+		return fmt.Errorf("%w: %s", ErrNotFlushed, printable(name))
+	}
+
+	// find the buckets in the top-level bucket.
+	rts := roots(db.State.Buckets)
+
+	var bkts []tempdb.Bucket
+
+	for _, bkt := range db.State.Buckets {
+		if rts[bkt.ID] == string(name) {
+			bkts = append(bkts, bkt)
+		}
+	}
+
+	if len(bkts) == 0 {
+		return walletdb.ErrBucketNotFound
+	}
+
+	recs, err := db.cfg.load(db.backend)
+	if err != nil {
+		return err
+	}
+
+	// ensure every record of the bucket is stored, in a stable order.
+	var missing []tempdb.BucketID
+
+	for key, rt := range db.records {
+		if _, ok := recs.Buckets[key]; !ok && rt == string(name) {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("record %d: %w", slices.Min(missing), ErrCorruptKey)
+	}
+
+	// decode the bucket's records and the records the database doesn't know about, in a stable order.
+	var keys []tempdb.BucketID
+
+	for key := range recs.Buckets {
+		if rt, ok := db.records[key]; !ok || rt == string(name) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	// decode with a copy of the config, decoding keeps the extra data of newer records in it.
+	cfg := *db.cfg
+	cfg.extra = nil
+
+	stored := make(map[tempdb.BucketID]tempdb.Bucket)
+
+	for _, key := range keys {
+		bkt, err := cfg.decodeRecord(key, recs.Buckets[key], db.plain)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", key, err)
+		}
+
+		stored[key] = bkt
+	}
+
+	_, err = unshard(stored)
+	if err != nil {
+		return err
+	}
+
+	// compare every key, in a stable order.
+	pths := paths(db.State.Buckets)
+
+	slices.SortFunc(bkts, func(a, b tempdb.Bucket) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	for _, bkt := range bkts {
+		s, ok := stored[bkt.ID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrCorruptKey, pths[bkt.ID])
+		}
+
+		ks := make([]string, 0, len(bkt.Value))
+
+		for k := range bkt.Value {
+			ks = append(ks, k)
+		}
+
+		slices.Sort(ks)
+
+		for _, k := range ks {
+			sv, ok := s.Value[k]
+			if !ok || !bytes.Equal(bkt.Value[k], sv) || (bkt.Value[k] == nil) != (sv == nil) {
+				return fmt.Errorf("%w: %s/%s", ErrCorruptKey, pths[bkt.ID], printable([]byte(k)))
+			}
+		}
+	}
+
+	// find the stored buckets the database doesn't have.
+	known := make(map[tempdb.BucketID]bool)
+
+	for _, bkt := range db.State.Buckets {
+		known[bkt.ID] = true
+	}
+
+	var unknown []tempdb.Bucket
+
+	for key, s := range stored {
+		if _, ok := shardOf(key); !ok && !known[s.ID] {
+			unknown = append(unknown, s)
+		}
+	}
+
+	// ensure none of them are in the bucket.
+	srts := roots(append(slices.Clone(db.State.Buckets), unknown...))
+
+	for _, key := range keys {
+		if s, ok := stored[key]; ok && !known[s.ID] && srts[s.ID] == string(name) {
+			return fmt.Errorf("record %d: %w", key, ErrCorruptKey)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+func TestValidateBucket(t *testing.T) {
+	db, err := walletdb.Create("localdb", "validate.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	ldb := db.(*DB)
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		bkt, err := tx.CreateTopLevelBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"a", "b", "c"} {
+			err = bkt.Put([]byte(k), []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.ValidateBucket([]byte("bucket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// break an entry in the stored record.
+	bkt := ldb.State.Buckets[0]
+	bkt.Value = map[string][]byte{"a": []byte("value"), "b": []byte("broken"), "c": []byte("value")}
+
+	v, err := encode(&bkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{bkt.ID: v},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.ValidateBucket([]byte("bucket"))
+	if !errors.Is(err, ErrCorruptKey) || !strings.HasSuffix(err.Error(), "bucket/b") {
+		t.Fatalf("expected b to be corrupt: got %v", err)
+	}
+
+	// store a record that can't be decoded.
+	err = ldb.backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{bkt.ID: []byte("garbage")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.ValidateBucket([]byte("bucket"))
+	if !errors.Is(err, ErrMalformedRecord) {
+		t.Fatalf("expected %v: got %v", ErrMalformedRecord, err)
+	}
+}
+
+func TestValidateBucketStored(t *testing.T) {
+	db, err := walletdb.Create("localdb", "validate-stored.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	ldb := db.(*DB)
+
+	putValue(t, db, "bucket", "value")
+
+	bkt := ldb.State.Buckets[0]
+
+	// store the bucket with a field from a newer version.
+	v, err := gobCodec{}.encodeExtra(&bkt, map[string][]byte{"future": []byte("field")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{bkt.ID: v},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.ValidateBucket([]byte("bucket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure validating doesn't change the extra data the bucket is written with.
+	if len(ldb.cfg.extra) != 0 {
+		t.Fatalf("expected no extra data: got %v", ldb.cfg.extra)
+	}
+
+	// store a nested bucket the database doesn't have.
+	nested := tempdb.Bucket{ID: 100, Parent: bkt.ID, Key: []byte("nested"), Value: map[string][]byte{}}
+
+	v, err = encode(&nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{nested.ID: v},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.ValidateBucket([]byte("bucket"))
+	if !errors.Is(err, ErrCorruptKey) || !strings.HasPrefix(err.Error(), "record 100:") {
+		t.Fatalf("expected record 100 to be corrupt: got %v", err)
+	}
+}
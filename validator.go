@@ -0,0 +1,83 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/linden/tempdb"
+)
+
+// a value was rejected by the bucket's validator, see `WithValidator`.
+var ErrInvalidValue = errors.New("value rejected by validator")
+
+// check every value put in the top-level bucket or its nested buckets with fn, such as to enforce the value's format.
+// the values are checked when the transaction commits, before it's applied, an error rolls the transaction back so
+// nothing is stored. the option can be passed for every bucket that's validated.
+func WithValidator(name []byte, fn func(key, value []byte) error) Option {
+	return func(cfg *config) {
+		if cfg.validators == nil {
+			cfg.validators = make(map[string]func(key, value []byte) error)
+		}
+
+		cfg.validators[string(name)] = fn
+	}
+}
+
+// check the values the transaction put, given the buckets from before it.
+func (cfg *config) validate(prev []tempdb.Bucket, next []tempdb.Bucket) error {
+	if len(cfg.validators) == 0 {
+		return nil
+	}
+
+	// index the buckets from before the transaction.
+	old := make(map[tempdb.BucketID]*tempdb.Bucket)
+
+	for i := range prev {
+		old[prev[i].ID] = &prev[i]
+	}
+
+	rts := roots(next)
+
+	for _, bkt := range next {
+		fn, ok := cfg.validators[rts[bkt.ID]]
+		if !ok {
+			continue
+		}
+
+		// check the keys in a stable order.
+		keys := make([]string, 0, len(bkt.Value))
+
+		for k := range bkt.Value {
+			keys = append(keys, k)
+		}
+
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			v := bkt.Value[k]
+
+			// skip nested buckets, they're stored as keys with a nil value.
+			if v == nil {
+				continue
+			}
+
+			// skip values that didn't change.
+			if o, ok := old[bkt.ID]; ok {
+				if ov, ok := o.Value[k]; ok && bytes.Equal(ov, v) {
+					continue
+				}
+			}
+
+			err := fn([]byte(k), v)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidValue, printable([]byte(k)), err)
+			}
+		}
+	}
+
+	return nil
+}
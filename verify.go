@@ -0,0 +1,102 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/linden/tempdb"
+)
+
+// check that the stored database is consistent, reading it in a fresh read transaction without loading it into or
+// changing the in-memory state, so it can run periodically on a live database. every record is decoded and checked
+// for duplicates and missing parents like `Repair`, and the count is checked like `Open`. every problem is returned.
+// flushes wait for the check so it doesn't see one half written, reads don't.
+func (db *DB) Verify() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	recs, err := db.cfg.load(db.backend)
+	if err != nil {
+		return err
+	}
+
+	// decode with a copy of the config, decoding keeps the extra data of newer records in it.
+	cfg := *db.cfg
+	cfg.extra = nil
+
+	return cfg.verify(recs)
+}
+
+// check the stored records are consistent.
+func (cfg *config) verify(recs *Records) error {
+	var errs []error
+
+	// ensure every stored bucket was loaded.
+	if v, ok := recs.Meta[countKey]; ok {
+		count, err := strconv.Atoi(string(v))
+		if err != nil {
+			return err
+		}
+
+		if count != len(recs.Buckets) {
+			errs = append(errs, fmt.Errorf("%w: expected %d, got %d", ErrCountMismatch, count, len(recs.Buckets)))
+		}
+	}
+
+	plain, err := parseKeys(recs.Meta[plaintextKey])
+	if err != nil {
+		return err
+	}
+
+	keys := make([]tempdb.BucketID, 0, len(recs.Buckets))
+
+	for key := range recs.Buckets {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	// decode every record, by the ID of its bucket.
+	bkts := make(map[tempdb.BucketID]tempdb.Bucket)
+
+	for _, key := range keys {
+		bkt, err := cfg.decodeRecord(key, recs.Buckets[key], plain)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("record %d: %w", key, err))
+			continue
+		}
+
+		// shards are decoded with their record key as their ID.
+		if _, ok := bkts[bkt.ID]; ok {
+			errs = append(errs, fmt.Errorf("record %d: %w", key, ErrDuplicate))
+			continue
+		}
+
+		bkts[bkt.ID] = bkt
+	}
+
+	// ensure every parent is stored.
+	for _, key := range keys {
+		bkt, ok := bkts[key]
+		if !ok {
+			continue
+		}
+
+		parent := bkt.Parent
+
+		// shards belong to their bucket.
+		if id, ok := shardOf(key); ok {
+			parent = id
+		}
+
+		if _, ok := bkts[parent]; !ok && parent != tempdb.RootBucketID {
+			errs = append(errs, fmt.Errorf("record %d: %w", key, ErrOrphan))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,168 @@
+//go:build js && wasm
+
+package localdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/linden/tempdb"
+)
+
+func TestVerify(t *testing.T) {
+	db, err := walletdb.Create("localdb", "verify-live.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ldb := db.(*DB)
+
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for i := 0; i < 10; i++ {
+			bkt, err := tx.CreateTopLevelBucket([]byte(fmt.Sprintf("bucket-%d", i)))
+			if err != nil {
+				return err
+			}
+
+			err = bkt.Put([]byte("key"), []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := ldb.State
+
+	// verify while reading.
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 5)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			errs <- ldb.Verify()
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		err = walletdb.View(db, func(tx walletdb.ReadTx) error {
+			if v := tx.ReadBucket([]byte("bucket-0")).Get([]byte("key")); string(v) != "value" {
+				t.Fatalf("expected value: got %s", v)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ensure the in-memory state wasn't replaced or changed.
+	if ldb.State != state || len(state.Buckets) != 10 {
+		t.Fatalf("expected the state to be untouched: got %d buckets", len(ldb.State.Buckets))
+	}
+
+	// store a record that isn't counted and can't be decoded.
+	err = ldb.backend.Write(&Changes{
+		Records: Records{
+			Buckets: map[tempdb.BucketID][]byte{
+				999: []byte("garbage"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ldb.Verify()
+	if !errors.Is(err, ErrCountMismatch) {
+		t.Fatalf("expected %v: got %v", ErrCountMismatch, err)
+	}
+}
+
+func TestVerifyDeletedBucket(t *testing.T) {
+	db, err := walletdb.Create("localdb", "verify-deleted.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	// create 2 top-level buckets, each with a nested bucket holding another.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		for _, nm := range []string{"deleted", "kept"} {
+			bkt, err := tx.CreateTopLevelBucket([]byte(nm))
+			if err != nil {
+				return err
+			}
+
+			nbkt, err := bkt.CreateBucket([]byte("nested"))
+			if err != nil {
+				return err
+			}
+
+			dbkt, err := nbkt.CreateBucket([]byte("deeper"))
+			if err != nil {
+				return err
+			}
+
+			err = dbkt.Put([]byte("key"), []byte("value"))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// delete a top-level bucket, and a nested bucket of the other.
+	err = walletdb.Update(db, func(tx walletdb.ReadWriteTx) error {
+		err := tx.DeleteTopLevelBucket([]byte("deleted"))
+		if err != nil {
+			return err
+		}
+
+		return tx.ReadWriteBucket([]byte("kept")).DeleteNestedBucket([]byte("nested"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ensure the buckets nested in them were deleted too.
+	bkts, keys, err := db.(*DB).Counts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bkts != 1 || keys != 0 {
+		t.Fatalf("expected 1 bucket and no keys: got %d buckets and %d keys", bkts, keys)
+	}
+
+	err = db.(*DB).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+}